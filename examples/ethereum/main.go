@@ -0,0 +1,120 @@
+// Command ethereum compresses a batch of RLP-encoded Ethereum transactions,
+// using the first ones as the dictionary, and reports the L1 calldata gas saved.
+//
+// Usage:
+//
+//	go run ./examples/ethereum [-txs transactions.json]
+//
+// The input file holds a JSON array of hex-encoded (optionally 0x-prefixed) transactions.
+// Without it, synthetic transactions from lzss/bench are used.
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/consensys/compress/lzss"
+	"github.com/consensys/compress/lzss/bench"
+)
+
+// nbDictTxs is the number of transactions used to build the dictionary.
+const nbDictTxs = 100
+
+func main() {
+	txsFile := flag.String("txs", "", "JSON file of hex-encoded RLP transactions; synthetic data if empty")
+	flag.Parse()
+
+	if err := run(os.Stdout, *txsFile); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(w io.Writer, txsFile string) error {
+	txs, err := loadTransactions(txsFile)
+	if err != nil {
+		return err
+	}
+	if len(txs) <= nbDictTxs {
+		return fmt.Errorf("need more than %d transactions, got %d", nbDictTxs, len(txs))
+	}
+
+	dict := bytes.Join(txs[:nbDictTxs], nil)
+	input := bytes.Join(txs[nbDictTxs:], nil)
+
+	// the dictionary is indexed once, when the compressor is created
+	start := time.Now()
+	compressor, err := lzss.NewCompressor(dict)
+	if err != nil {
+		return err
+	}
+	dictTime := time.Since(start)
+
+	start = time.Now()
+	c, err := compressor.Compress(input)
+	if err != nil {
+		return err
+	}
+	compressTime := time.Since(start)
+
+	d, err := lzss.Decompress(c, dict)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(d, input) {
+		return errors.New("round trip failed")
+	}
+
+	fmt.Fprintf(w, "transactions:     %d (+%d in the dictionary)\n", len(txs)-nbDictTxs, nbDictTxs)
+	fmt.Fprintf(w, "original size:    %d bytes\n", len(input))
+	fmt.Fprintf(w, "compressed size:  %d bytes (ratio %.2f)\n", len(c), float64(len(input))/float64(len(c)))
+	fmt.Fprintf(w, "calldata gas:     %d -> %d\n", calldataGas(input), calldataGas(c))
+	fmt.Fprintf(w, "dictionary index: %s\n", dictTime)
+	fmt.Fprintf(w, "compression time: %s\n", compressTime)
+	return nil
+}
+
+// loadTransactions reads the transactions from file, or generates them if file is empty.
+func loadTransactions(file string) ([][]byte, error) {
+	if file == "" {
+		return bench.LoadEthereumTransactions(), nil
+	}
+
+	f, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var encoded []string
+	if err = json.Unmarshal(f, &encoded); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", file, err)
+	}
+	txs := make([][]byte, len(encoded))
+	for i := range encoded {
+		if txs[i], err = hex.DecodeString(strings.TrimPrefix(encoded[i], "0x")); err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i, err)
+		}
+	}
+	return txs, nil
+}
+
+// calldataGas returns the L1 gas cost of posting data as calldata:
+// 16 per nonzero byte and 4 per zero byte.
+func calldataGas(data []byte) int {
+	gas := 0
+	for _, b := range data {
+		if b == 0 {
+			gas += 4
+		} else {
+			gas += 16
+		}
+	}
+	return gas
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunSynthetic(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, run(&out, ""))
+	assert.Contains(t, out.String(), "compressed size:")
+}
+
+func TestRunFromFile(t *testing.T) {
+	txs := make([]string, nbDictTxs+10)
+	for i := range txs {
+		txs[i] = "0xf86c0a8502540be400825208944bbeeb066ed09b7aed07bf39eee0460dfa261520880de0b6b3a7640000801ca0"
+	}
+	file := filepath.Join(t.TempDir(), "txs.json")
+	encoded, err := json.Marshal(txs)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(file, encoded, 0600))
+
+	var out bytes.Buffer
+	require.NoError(t, run(&out, file))
+	assert.Contains(t, out.String(), "transactions:     10 (+100 in the dictionary)")
+}
+
+func TestRunTooFewTransactions(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "txs.json")
+	require.NoError(t, os.WriteFile(file, []byte(`["0x00"]`), 0600))
+	assert.Error(t, run(&bytes.Buffer{}, file))
+}
+
+func TestCalldataGas(t *testing.T) {
+	assert.Equal(t, 0, calldataGas(nil))
+	assert.Equal(t, 4+16+4, calldataGas([]byte{0, 1, 0}))
+}
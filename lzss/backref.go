@@ -18,6 +18,13 @@ const (
 	shortAddrBits          = 14 // number of bits to encode the address in a short backref
 )
 
+// estimated number of constraints to decode a back-reference, on top of
+// the binary decomposition of its length and address fields
+const (
+	backrefBaseConstraints = 3 // delimiter check, copy source lookup and output write
+	backrefDictConstraints = 1 // selecting between the dictionary and the decompressed output
+)
+
 type BackrefType struct {
 	Delimiter      byte
 	NbBitsAddress  uint8
@@ -27,6 +34,7 @@ type BackrefType struct {
 	maxAddress     int
 	maxLength      int
 	DictLen        int
+	nbConstraints  int
 }
 
 func NewShortBackrefType() (short BackrefType) {
@@ -49,7 +57,45 @@ func newBackRefType(symbol byte, nbBitsAddress, nbBitsLength uint8, dictLen int)
 		maxAddress:     1 << nbBitsAddress,
 		maxLength:      1 << nbBitsLength,
 		DictLen:        dictLen,
+		nbConstraints:  nbConstraintsBackRef(nbBitsAddress, nbBitsLength, dictLen),
+	}
+}
+
+func nbConstraintsBackRef(nbBitsAddress, nbBitsLength uint8, dictLen int) int {
+	n := int(nbBitsAddress) + int(nbBitsLength) + backrefBaseConstraints
+	if dictLen > 0 {
+		n += backrefDictConstraints
+	}
+	return n
+}
+
+// NbConstraints returns an estimate of the number of SNARK constraints
+// needed to decode a single back-reference of this type.
+func (b BackrefType) NbConstraints() int {
+	return b.nbConstraints
+}
+
+// Savings returns the number of bits saved by encoding length bytes found at addr
+// as a back-reference of this type rather than as literals. It may be negative.
+// Since the fields have a fixed width, it does not depend on addr.
+// If addr or length is -1, as returned when no match is found, it returns math.MinInt.
+func (b BackrefType) Savings(addr, length int) int {
+	if addr < 0 || length < 1 {
+		return math.MinInt
 	}
+	return 8*length - b.Cost()
+}
+
+// Cost returns the number of bits needed to encode a back-reference of this type:
+// the delimiter symbol, followed by the length and address fields.
+func (b BackrefType) Cost() int {
+	return int(b.NbBitsBackRef)
+}
+
+// Overlaps reports whether b and other share the same delimiter symbol,
+// in which case the decompressor could not tell them apart.
+func (b BackrefType) Overlaps(other BackrefType) bool {
+	return b.Delimiter == other.Delimiter
 }
 
 type backref struct {
@@ -58,6 +104,11 @@ type backref struct {
 	bType   BackrefType
 }
 
+// Equal reports whether b and other have the same type, address and length.
+func (b backref) Equal(other backref) bool {
+	return b.bType == other.bType && b.address == other.address && b.length == other.length
+}
+
 // Warning; writeTo and readFrom are not symmetrical
 
 func (b *backref) writeTo(w writer, i int) {
@@ -86,8 +137,5 @@ func (b *backref) readFrom(r *bitio.Reader) error {
 }
 
 func (b *backref) savings() int {
-	if b.length == -1 {
-		return math.MinInt // -1 is a special value
-	}
-	return 8*b.length - int(b.bType.NbBitsBackRef)
+	return b.bType.Savings(b.address, b.length)
 }
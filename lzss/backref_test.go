@@ -0,0 +1,194 @@
+package lzss
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/icza/bitio"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackrefNbConstraints(t *testing.T) {
+	assert := require.New(t)
+
+	short := NewShortBackrefType()
+	assert.Equal(shortAddrBits+maxBackrefLenLog2+backrefBaseConstraints, short.NbConstraints())
+
+	// a dynamic backref can reach into the dictionary, which costs an extra selection
+	dynamic := NewDynamicBackrefType(100, 0)
+	assert.Equal(int(dynamic.NbBitsAddress)+maxBackrefLenLog2+backrefBaseConstraints+backrefDictConstraints, dynamic.NbConstraints())
+	assert.Greater(dynamic.NbConstraints(), short.NbConstraints())
+
+	// without a dictionary, only the field widths matter
+	assert.Equal(dynamic.NbConstraints()-backrefDictConstraints, NewDynamicBackrefType(0, 0).NbConstraints())
+}
+
+func TestBackrefCost(t *testing.T) {
+	assert := require.New(t)
+
+	short := NewShortBackrefType()
+	assert.Equal(8+shortAddrBits+maxBackrefLenLog2, short.Cost())
+	b := backref{bType: short, address: 1, length: 10}
+	assert.Equal(8*b.length-short.Cost(), b.savings())
+
+	dynamic := NewDynamicBackrefType(100, 0)
+	assert.Equal(8+int(dynamic.NbBitsAddress)+maxBackrefLenLog2, dynamic.Cost())
+	assert.Equal(int(dynamic.NbBitsBackRef), dynamic.Cost())
+}
+
+// shortestFinder returns the previous byte as a match of the minimum length
+type shortestFinder struct{}
+
+func (shortestFinder) Reset([]byte) {}
+
+func (shortestFinder) FindLongest(_ []byte, pos int, _ BackrefType, minLen int) (addr, length int) {
+	return pos - 1, minLen
+}
+
+func TestFindBackRefLengthBounds(t *testing.T) {
+	assert := require.New(t)
+
+	compressor, err := NewCompressor(getDictionary())
+	assert.NoError(err)
+	dictLen := len(compressor.dictData)
+
+	data := append([]byte("abcdefgh"), make([]byte, 1000)...)
+	finder := NewSuffixArrayFinder()
+	finder.Reset(data)
+
+	for _, bType := range []BackrefType{NewShortBackrefType(), NewDynamicBackrefType(dictLen, 0)} {
+		// a minimum length of 0 is raised to 1, the shortest encodable backref
+		addr, length := findBackRef(data, 9, bType, 0, finder, compressor.dictIndex, dictLen)
+		assert.NotEqual(-1, addr)
+		assert.GreaterOrEqual(length, 1)
+
+		// even with a finder returning the shortest match allowed
+		_, length = findBackRef(data, 500, bType, 0, shortestFinder{}, compressor.dictIndex, dictLen)
+		assert.GreaterOrEqual(length, 1)
+
+		// lengths are capped by the backref type
+		_, length = findBackRef(data, 300, bType, 0, finder, compressor.dictIndex, dictLen)
+		assert.Equal(bType.maxLength, length)
+
+		// and by the end of the data
+		_, length = findBackRef(data, len(data)-10, bType, 0, finder, compressor.dictIndex, dictLen)
+		assert.Equal(10, length)
+		_, length = findBackRef(data, len(data)-1, bType, 0, finder, compressor.dictIndex, dictLen)
+		assert.Equal(1, length)
+	}
+}
+
+func TestBackrefTypesOverlap(t *testing.T) {
+	assert := require.New(t)
+
+	short, dynamic := NewShortBackrefType(), NewDynamicBackrefType(100, 0)
+	assert.False(short.Overlaps(dynamic))
+	assert.False(dynamic.Overlaps(short))
+
+	// same delimiter, whatever the field widths
+	assert.True(short.Overlaps(short))
+	assert.True(dynamic.Overlaps(NewDynamicBackrefType(0, 0)))
+	assert.True(short.Overlaps(newBackRefType(SymbolShort, 21, maxBackrefLenLog2, 0)))
+}
+
+func TestBackrefTypeSavings(t *testing.T) {
+	assert := require.New(t)
+
+	for _, bType := range []BackrefType{NewShortBackrefType(), NewDynamicBackrefType(100, 0)} {
+		assert.Equal(8*10-int(bType.NbBitsBackRef), bType.Savings(0, 10))
+		assert.Equal(bType.Savings(0, 10), bType.Savings(1000, 10))
+		assert.Negative(bType.Savings(0, 1))
+
+		b := backref{bType: bType, address: 3, length: 50}
+		assert.Equal(b.savings(), bType.Savings(b.address, b.length))
+
+		// no match
+		assert.Equal(math.MinInt, bType.Savings(-1, -1))
+		b = backref{bType: bType, address: -1, length: -1}
+		assert.Equal(math.MinInt, b.savings())
+	}
+}
+
+func TestBackrefEqual(t *testing.T) {
+	assert := require.New(t)
+
+	b := backref{bType: NewShortBackrefType(), address: 10, length: 5}
+	assert.True(b.Equal(b))
+
+	other := b
+	other.address++
+	assert.False(b.Equal(other))
+
+	other = b
+	other.length++
+	assert.False(b.Equal(other))
+
+	other = b
+	other.bType = NewDynamicBackrefType(0, 0)
+	assert.False(b.Equal(other))
+
+	// the dictionary length is part of the type
+	d := backref{bType: NewDynamicBackrefType(100, 0), address: 10, length: 5}
+	other = d
+	other.bType = NewDynamicBackrefType(101, 0)
+	assert.False(d.Equal(other))
+}
+
+// FuzzSmallBackrefRoundTrip writes 1 to 3 byte short and dynamic backrefs, which are shorter
+// than what the compressor would normally emit, and checks that they decode correctly.
+func FuzzSmallBackrefRoundTrip(f *testing.F) {
+	f.Add(uint8(0), uint16(0), false)
+	f.Add(uint8(1), uint16(1), true)
+	f.Add(uint8(2), uint16(300), false)
+
+	f.Fuzz(func(t *testing.T, length uint8, distance uint16, dynamic bool) {
+		assert := require.New(t)
+
+		dict := AugmentDict(nil)
+		b := backref{length: 1 + int(length)%3}
+
+		// literals to copy from
+		prefix := make([]byte, 1+int(distance)%1024)
+		for i := range prefix {
+			prefix[i] = byte(i % int(SymbolShort))
+		}
+		i := len(prefix)
+		distance = uint16(1 + int(distance)%len(prefix))
+
+		if dynamic {
+			b.bType = NewDynamicBackrefType(len(dict), i)
+			b.address = len(dict) + i - int(distance)
+		} else {
+			b.bType = NewShortBackrefType()
+			b.address = i - int(distance)
+		}
+
+		var buf bytes.Buffer
+		header := Header{Version: Version}
+		_, err := header.WriteTo(&buf)
+		assert.NoError(err)
+		buf.Write(prefix)
+		w := bitio.NewWriter(&buf)
+		b.writeTo(w, i)
+		_, err = w.Align()
+		assert.NoError(err)
+
+		// read the backref back
+		r := bitio.NewReader(bytes.NewReader(buf.Bytes()[HeaderSize+len(prefix):]))
+		assert.Equal(b.bType.Delimiter, r.TryReadByte())
+		read := backref{bType: b.bType}
+		assert.NoError(read.readFrom(r))
+		// readFrom returns the distance rather than the absolute address
+		assert.True(read.Equal(backref{bType: b.bType, address: int(distance), length: b.length}), "read %v", read)
+
+		// and decompress the whole thing
+		expected := append([]byte{}, prefix...)
+		for j := 0; j < b.length; j++ {
+			expected = append(expected, expected[len(expected)-int(distance)])
+		}
+		d, err := Decompress(buf.Bytes(), nil)
+		assert.NoError(err)
+		assert.Equal(expected, d)
+	})
+}
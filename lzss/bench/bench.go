@@ -0,0 +1,217 @@
+// Package bench provides standard, generated inputs for the compression benchmarks,
+// so that results can be compared across machines and changes.
+// All inputs are deterministic and together weigh less than 1MB.
+package bench
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+)
+
+const (
+	nbBlocks     = 8
+	nbTxPerBlock = 150
+
+	nbRandomInputs = 4
+	randomSize     = 1 << 16
+
+	nbRepetitiveInputs = 4
+	repetitiveSize     = 1 << 15
+)
+
+// LoadEthereumBlocks returns synthetic RLP-encoded Ethereum blocks with legacy transactions.
+// Hashes and signatures are random, while addresses, selectors and amounts are drawn from
+// small pools, to mimic the redundancy of real blocks.
+func LoadEthereumBlocks() [][]byte {
+	blocks, _ := ethereumBlocks()
+	return blocks
+}
+
+// LoadEthereumTransactions returns the RLP-encoded transactions of the blocks
+// returned by LoadEthereumBlocks, in order.
+func LoadEthereumTransactions() [][]byte {
+	_, txs := ethereumBlocks()
+	return txs
+}
+
+func ethereumBlocks() (blocks, allTxs [][]byte) {
+	rng := rand.New(rand.NewSource(0)) //#nosec G404 -- benchmark input
+
+	addresses := make([][]byte, 128)
+	for i := range addresses {
+		addresses[i] = randomBytes(rng, 20)
+	}
+	selectors := make([][]byte, 16)
+	for i := range selectors {
+		selectors[i] = randomBytes(rng, 4)
+	}
+	uncleHash := randomBytes(rng, 32)
+
+	blocks = make([][]byte, nbBlocks)
+	for b := range blocks {
+		txs := make([][]byte, nbTxPerBlock)
+		for i := range txs {
+			var data []byte
+			if rng.Intn(4) != 0 {
+				selector := selectors[rng.Intn(len(selectors))]
+				data = appendCall(nil, rng, selector, rng.Intn(4), addresses)
+			}
+
+			txs[i] = rlpList(
+				rlpUint(uint64(rng.Intn(5000))),                   // nonce
+				rlpUint(uint64(rng.Intn(100)+1)*1_000_000_000),    // gas price
+				rlpUint(uint64(21000+rng.Intn(10)*10000)),         // gas limit
+				rlpBytes(addresses[rng.Intn(len(addresses))]),     // to
+				rlpUint(uint64(rng.Intn(1000))*1_000_000_000_000), // value
+				rlpBytes(data),
+				rlpUint(uint64(37+rng.Intn(2))), // v
+				rlpBytes(randomBytes(rng, 32)),  // r
+				rlpBytes(randomBytes(rng, 32)),  // s
+			)
+		}
+
+		bloom := make([]byte, 256)
+		for i := 0; i < 20; i++ {
+			bloom[rng.Intn(len(bloom))] |= 1 << rng.Intn(8)
+		}
+
+		header := rlpList(
+			rlpBytes(randomBytes(rng, 32)), // parent hash
+			rlpBytes(uncleHash),
+			rlpBytes(addresses[rng.Intn(len(addresses))]), // coinbase
+			rlpBytes(randomBytes(rng, 32)),                // state root
+			rlpBytes(randomBytes(rng, 32)),                // transactions root
+			rlpBytes(randomBytes(rng, 32)),                // receipts root
+			rlpBytes(bloom),
+			rlpUint(0),                                    // difficulty
+			rlpUint(uint64(18_000_000+b)),                 // number
+			rlpUint(30_000_000),                           // gas limit
+			rlpUint(uint64(rng.Intn(30_000_000))),         // gas used
+			rlpUint(uint64(1_700_000_000+12*b)),           // timestamp
+			rlpBytes(nil),                                 // extra data
+			rlpBytes(randomBytes(rng, 32)),                // mix hash
+			rlpBytes(make([]byte, 8)),                     // nonce
+			rlpUint(uint64(rng.Intn(50)+1)*1_000_000_000), // base fee
+		)
+
+		blocks[b] = rlpList(header, rlpList(txs...), rlpList())
+		allTxs = append(allTxs, txs...)
+	}
+	return
+}
+
+// EthereumCalldata returns synthetic, deterministic EVM calldata for txCount transactions.
+// Each transaction is a 4-byte function selector followed by ABI-encoded 32-byte arguments:
+// addresses and amounts are drawn from small pools and padded with zeros, as in real calldata.
+func EthereumCalldata(txCount int) []byte {
+	rng := rand.New(rand.NewSource(int64(txCount))) //#nosec G404 -- benchmark input
+
+	selectors := [][]byte{
+		{0xa9, 0x05, 0x9c, 0xbb}, // transfer(address,uint256)
+		{0x09, 0x5e, 0xa7, 0xb3}, // approve(address,uint256)
+		{0x23, 0xb8, 0x72, 0xdd}, // transferFrom(address,address,uint256)
+		{0x38, 0xed, 0x17, 0x39}, // swapExactTokensForTokens(uint256,uint256,address[],address,uint256)
+	}
+	nbArgs := []int{2, 2, 3, 5}
+
+	addresses := make([][]byte, 64)
+	for i := range addresses {
+		addresses[i] = randomBytes(rng, 20)
+	}
+
+	var res []byte
+	for tx := 0; tx < txCount; tx++ {
+		f := rng.Intn(len(selectors))
+		res = appendCall(res, rng, selectors[f], nbArgs[f], addresses)
+	}
+	return res
+}
+
+// appendCall appends to dst a call to selector with nbArgs zero-padded ABI words,
+// each either an address from addresses or an amount.
+func appendCall(dst []byte, rng *rand.Rand, selector []byte, nbArgs int, addresses [][]byte) []byte {
+	dst = append(dst, selector...)
+	for arg := 0; arg < nbArgs; arg++ {
+		var word [32]byte
+		if rng.Intn(2) == 0 {
+			copy(word[12:], addresses[rng.Intn(len(addresses))])
+		} else {
+			// amounts are mostly small, with a round-ish decimal look
+			binary.BigEndian.PutUint64(word[24:], uint64(rng.Intn(1_000_000))*1_000_000_000)
+		}
+		dst = append(dst, word[:]...)
+	}
+	return dst
+}
+
+// LoadRandomData returns incompressible inputs, deterministically derived from seed.
+func LoadRandomData(seed int64) [][]byte {
+	rng := rand.New(rand.NewSource(seed)) //#nosec G404 -- benchmark input
+	res := make([][]byte, nbRandomInputs)
+	for i := range res {
+		res[i] = randomBytes(rng, randomSize)
+	}
+	return res
+}
+
+// LoadRepetitiveData returns highly compressible inputs: long runs of a single byte,
+// a short repeated pattern, and a longer one.
+func LoadRepetitiveData() [][]byte {
+	rng := rand.New(rand.NewSource(0)) //#nosec G404 -- benchmark input
+	patterns := [][]byte{
+		{0},
+		{0xff},
+		[]byte("0123456789abcdef"),
+		randomBytes(rng, 1000),
+	}
+	res := make([][]byte, nbRepetitiveInputs)
+	for i := range res {
+		res[i] = bytes.Repeat(patterns[i], repetitiveSize/len(patterns[i])+1)[:repetitiveSize]
+	}
+	return res
+}
+
+func randomBytes(rng *rand.Rand, n int) []byte {
+	res := make([]byte, n)
+	rng.Read(res)
+	return res
+}
+
+// rlpBytes returns the RLP encoding of a byte string.
+func rlpBytes(b []byte) []byte {
+	if len(b) == 1 && b[0] < 0x80 {
+		return []byte{b[0]}
+	}
+	return append(rlpPrefix(0x80, len(b)), b...)
+}
+
+// rlpUint returns the RLP encoding of an integer, as its minimal big-endian representation.
+func rlpUint(v uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	i := 0
+	for i < len(b) && b[i] == 0 {
+		i++
+	}
+	return rlpBytes(b[i:])
+}
+
+// rlpList returns the RLP encoding of a list of already encoded items.
+func rlpList(items ...[]byte) []byte {
+	payload := bytes.Join(items, nil)
+	return append(rlpPrefix(0xc0, len(payload)), payload...)
+}
+
+func rlpPrefix(offset byte, length int) []byte {
+	if length < 56 {
+		return []byte{offset + byte(length)}
+	}
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(length))
+	i := 0
+	for b[i] == 0 {
+		i++
+	}
+	return append([]byte{offset + 55 + byte(len(b)-i)}, b[i:]...)
+}
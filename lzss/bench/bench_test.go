@@ -0,0 +1,40 @@
+package bench
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInputsDeterministicAndSmall(t *testing.T) {
+	total := 0
+	for name, load := range map[string]func() [][]byte{
+		"blocks":     LoadEthereumBlocks,
+		"random":     func() [][]byte { return LoadRandomData(1) },
+		"repetitive": LoadRepetitiveData,
+	} {
+		inputs := load()
+		assert.NotEmpty(t, inputs, name)
+		assert.Equal(t, inputs, load(), name)
+		for _, in := range inputs {
+			total += len(in)
+		}
+	}
+	assert.Less(t, total, 1<<20)
+	assert.NotEqual(t, LoadRandomData(1), LoadRandomData(2))
+}
+
+func TestEthereumBlocksAreRLPLists(t *testing.T) {
+	for _, block := range LoadEthereumBlocks() {
+		// a long list: 0xf7+n, followed by the n-byte big-endian payload length
+		if !assert.Greater(t, block[0], byte(0xf7)) {
+			continue
+		}
+		n := int(block[0] - 0xf7)
+		length := 0
+		for _, b := range block[1 : 1+n] {
+			length = length<<8 | int(b)
+		}
+		assert.Equal(t, len(block), 1+n+length)
+	}
+}
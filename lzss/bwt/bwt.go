@@ -0,0 +1,91 @@
+// Package bwt implements the Burrows-Wheeler transform, for comparing
+// BWT-based pipelines with lzss on the same data.
+//
+// The transform used here appends an implicit end-of-data sentinel, smaller than
+// any byte, to the input. The sentinel is not part of the output; its position
+// is returned separately as the primary index.
+package bwt
+
+import (
+	"errors"
+
+	"github.com/consensys/compress/lzss/internal/suffixarray"
+)
+
+// BWT returns the Burrows-Wheeler transform of data, along with the primary index
+// needed to invert it. len(transformed) == len(data).
+func BWT(data []byte) (transformed []byte, primaryIndex int) {
+	if len(data) == 0 {
+		return []byte{}, 0
+	}
+
+	sa := make([]int32, len(data))
+	suffixarray.New(data, sa)
+
+	// row 0 of the sorted rotations starts with the sentinel and ends with the last byte;
+	// the other rows follow the order of the suffixes, each ending with the byte preceding it.
+	transformed = make([]byte, 0, len(data))
+	transformed = append(transformed, data[len(data)-1])
+	for i, s := range sa {
+		if s == 0 {
+			// this row ends with the sentinel
+			primaryIndex = i + 1
+			continue
+		}
+		transformed = append(transformed, data[s-1])
+	}
+	return
+}
+
+// InverseBWT reverts BWT.
+func InverseBWT(transformed []byte, primaryIndex int) ([]byte, error) {
+	n := len(transformed)
+	if n == 0 {
+		if primaryIndex != 0 {
+			return nil, errors.New("invalid primary index for empty data")
+		}
+		return []byte{}, nil
+	}
+	if primaryIndex < 1 || primaryIndex > n {
+		return nil, errors.New("primary index out of range")
+	}
+
+	// last returns the last byte of row r in the sorted rotations
+	last := func(r int) byte {
+		if r < primaryIndex {
+			return transformed[r]
+		}
+		return transformed[r-1]
+	}
+
+	// first[c] is the first row starting with byte c; row 0 starts with the sentinel
+	var first [256]int
+	for _, c := range transformed {
+		first[c]++
+	}
+	for c, sum := 0, 1; c < 256; c++ {
+		first[c], sum = sum, sum+first[c]
+	}
+
+	// lf maps a row to the row starting with its last byte
+	lf := make([]int32, n+1)
+	for r := 0; r <= n; r++ {
+		if r == primaryIndex {
+			continue
+		}
+		c := last(r)
+		lf[r] = int32(first[c])
+		first[c]++
+	}
+
+	// walk the data backwards, starting from the row beginning with the sentinel
+	res := make([]byte, n)
+	for i, r := n-1, 0; i >= 0; i-- {
+		if r == primaryIndex {
+			return nil, errors.New("corrupted transform")
+		}
+		res[i] = last(r)
+		r = int(lf[r])
+	}
+	return res, nil
+}
@@ -0,0 +1,66 @@
+package bwt
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testRoundTrip(t *testing.T, data []byte) {
+	t.Helper()
+	transformed, primaryIndex := BWT(data)
+	require.Equal(t, len(data), len(transformed))
+
+	back, err := InverseBWT(transformed, primaryIndex)
+	require.NoError(t, err)
+	require.True(t, bytes.Equal(data, back), "round trip failed")
+}
+
+func TestBanana(t *testing.T) {
+	// sorted rotations of "banana$": $banana, a$banan, ana$ban, anana$b, banana$, na$bana, nana$ba
+	transformed, primaryIndex := BWT([]byte("banana"))
+	require.Equal(t, "annbaa", string(transformed))
+	require.Equal(t, 4, primaryIndex)
+
+	testRoundTrip(t, []byte("banana"))
+}
+
+func TestEmpty(t *testing.T) {
+	testRoundTrip(t, nil)
+	testRoundTrip(t, []byte{})
+
+	_, err := InverseBWT(nil, 1)
+	require.Error(t, err)
+}
+
+func TestIdenticalBytes(t *testing.T) {
+	for _, n := range []int{1, 2, 3, 1000} {
+		testRoundTrip(t, make([]byte, n))
+		testRoundTrip(t, bytes.Repeat([]byte{0xff}, n))
+	}
+}
+
+func TestRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(0)) //#nosec G404 -- deterministic test input
+	for _, n := range []int{1, 10, 1000, 300000} {
+		data := make([]byte, n)
+		rng.Read(data)
+		testRoundTrip(t, data)
+
+		// small alphabet, lots of repetitions
+		for i := range data {
+			data[i] %= 3
+		}
+		testRoundTrip(t, data)
+	}
+}
+
+func TestInvalidPrimaryIndex(t *testing.T) {
+	transformed, _ := BWT([]byte("hello world"))
+	for _, primaryIndex := range []int{-1, 0, len(transformed) + 1} {
+		_, err := InverseBWT(transformed, primaryIndex)
+		require.Error(t, err)
+	}
+}
@@ -0,0 +1,32 @@
+package lzss_test
+
+import (
+	"testing"
+
+	"github.com/consensys/compress/lzss"
+	"github.com/consensys/compress/lzss/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalldataRoundTrip(t *testing.T) {
+	finders := map[string]lzss.MatchFinder{
+		"suffixarray": lzss.NewSuffixArrayFinder(),
+		"hashchain":   lzss.NewHashChainFinder(64),
+	}
+	for name, finder := range finders {
+		t.Run(name, func(t *testing.T) {
+			c, err := lzss.NewCompressor(nil, lzss.WithMatchFinder(finder))
+			require.NoError(t, err)
+
+			for _, txCount := range []int{0, 1, 10, 1000} {
+				calldata := testutil.EthereumCalldata(txCount)
+				compressed := testutil.AssertRoundTrip(t, c, nil, calldata)
+				if txCount >= 1000 {
+					testutil.AssertCompressionRatio(t, calldata, compressed, 2)
+				}
+			}
+
+			testutil.AssertRoundTrip(t, c, nil, testutil.RandomInput(0, 1<<14))
+		})
+	}
+}
@@ -2,7 +2,11 @@ package lzss
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
 
 	"github.com/consensys/compress/lzss/internal/suffixarray"
 	"github.com/icza/bitio"
@@ -20,28 +24,74 @@ type Compressor struct {
 	lastNbSkippedBits uint8
 	lastInLen         int
 
-	inputIndex *suffixarray.Index
-	inputSa    [MaxInputSize]int32 // suffix array space.
+	matchFinder MatchFinder
 
 	dictData        []byte
 	dictIndex       *suffixarray.Index
-	dictSa          [MaxDictSize]int32 // suffix array space.
-	dictReservedIdx map[byte]int       // stores the index of the reserved symbols in the dictionary
+	dictReservedIdx map[byte]int // stores the index of the reserved symbols in the dictionary
+
+	maxInputSize int
+	maxDictSize  int
+	magic        bool // whether to prefix the output with Magic
 
 	noCompression bool
 }
 
+// Option configures a Compressor.
+type Option func(*Compressor)
+
+// WithMatchFinder sets the MatchFinder used to find back-references within the input.
+// The default is a SuffixArrayFinder.
+func WithMatchFinder(f MatchFinder) Option {
+	return func(c *Compressor) {
+		c.matchFinder = f
+	}
+}
+
+// WithMaxInputSize sets the maximum number of bytes that can be written to the compressor
+// between two calls to Reset. It is clamped to [0, MaxInputSize], which is the default.
+func WithMaxInputSize(n int) Option {
+	return func(c *Compressor) {
+		c.maxInputSize = min(max(n, 0), MaxInputSize)
+	}
+}
+
+// WithMaxDictSize sets the maximum size of the dictionary, once augmented with the reserved symbols.
+// It is clamped to [0, MaxDictSize], which is the default.
+func WithMaxDictSize(n int) Option {
+	return func(c *Compressor) {
+		c.maxDictSize = min(max(n, 0), MaxDictSize)
+	}
+}
+
+// WithMagic makes the compressor start its output with Magic, so that it can be told apart
+// from other data, e.g. by SniffFrame. This costs len(Magic) bytes per compressed data.
+// Decompress accepts data with and without the prefix.
+func WithMagic() Option {
+	return func(c *Compressor) {
+		c.magic = true
+	}
+}
+
 // NewCompressor returns a new compressor with the given dictionary
 // The dictionary is an unstructured sequence of substrings that are expected to occur frequently in the data. It is not included in the compressed data and should thus be a-priori known to both the compressor and the decompressor.
 // The level determines the bit alignment of the compressed data. The "higher" the level, the better the compression ratio but the more constraints on the decompressor.
-func NewCompressor(dict []byte) (*Compressor, error) {
+func NewCompressor(dict []byte, opts ...Option) (*Compressor, error) {
 	dict = AugmentDict(dict)
-	if len(dict) > MaxDictSize {
-		return nil, fmt.Errorf("dict size must be <= %d", MaxDictSize)
-	}
 	c := &Compressor{
 		dictData:        dict,
 		dictReservedIdx: make(map[byte]int),
+		maxInputSize:    MaxInputSize,
+		maxDictSize:     MaxDictSize,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if len(dict) > c.maxDictSize {
+		return nil, fmt.Errorf("dict size must be <= %d", c.maxDictSize)
+	}
+	if c.matchFinder == nil {
+		c.matchFinder = NewSuffixArrayFinder()
 	}
 
 	// find the reserved symbols in the dictionary
@@ -58,15 +108,15 @@ func NewCompressor(dict []byte) (*Compressor, error) {
 		}
 	}
 
-	c.outBuf.Grow(MaxInputSize)
-	c.inBuf.Grow(1 << 19)
-	c.bw = bitio.NewWriter(&c.outBuf)
-	c.dictIndex = suffixarray.New(c.dictData, c.dictSa[:len(c.dictData)])
+	c.outBuf.Grow(c.maxInputSize)
+	c.inBuf.Grow(min(1<<19, c.maxInputSize))
+	c.dictIndex = suffixarray.New(c.dictData, make([]int32, len(c.dictData)))
 	c.Reset()
 	return c, nil
 }
 
-// AugmentDict ensures the dictionary contains the special symbols
+// AugmentDict ensures the dictionary contains the special symbols.
+// If either is missing, both are appended.
 func AugmentDict(dict []byte) []byte {
 
 	found := uint8(0)
@@ -87,8 +137,49 @@ func AugmentDict(dict []byte) []byte {
 	return append(dict, SymbolShort, SymbolDynamic)
 }
 
+// AugmentDictWithSymbols appends to dict, in order, the bytes of extra that it does not already contain.
+// Bytes repeated in extra are appended at most once.
+func AugmentDictWithSymbols(dict []byte, extra ...byte) []byte {
+	var found [256]bool
+	for _, b := range dict {
+		found[b] = true
+	}
+	for _, b := range extra {
+		if !found[b] {
+			dict = append(dict, b)
+			found[b] = true
+		}
+	}
+	return dict
+}
+
+// DictEntropy returns the Shannon entropy of the byte distribution of the dictionary,
+// including the reserved symbols, in bits per byte: from 0 for a single repeated byte to 8
+// when all 256 values are equally frequent.
+// A low entropy dictionary matches repetitive data well but covers few byte values;
+// a high entropy one covers more values, but its individual substrings are less likely to be matched.
+func (compressor *Compressor) DictEntropy() float64 {
+	var counts [256]int
+	for _, b := range compressor.dictData {
+		counts[b]++
+	}
+	entropy := 0.0
+	n := float64(len(compressor.dictData))
+	for _, c := range counts {
+		if c != 0 {
+			p := float64(c) / n
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return entropy
+}
+
 // The compressor cannot recover from a Write error. It must be Reset before writing again
 func (compressor *Compressor) Write(d []byte) (n int, err error) {
+	return compressor.writeWithProgress(d, nil)
+}
+
+func (compressor *Compressor) writeWithProgress(d []byte, progress func(done, total int) error) (n int, err error) {
 
 	// reconstruct bit writer cache
 	compressor.lastOutLen = compressor.outBuf.Len()
@@ -113,9 +204,9 @@ func (compressor *Compressor) Write(d []byte) (n int, err error) {
 	d = compressor.inBuf.Bytes()
 
 	// build the index
-	compressor.inputIndex = suffixarray.New(d, compressor.inputSa[:len(d)])
+	compressor.matchFinder.Reset(d)
 
-	n, err = compressor.write(compressor.bw, d, compressor.lastInLen, compressor.inputIndex)
+	n, err = compressor.write(compressor.bw, d, compressor.lastInLen, compressor.matchFinder, progress)
 	if err != nil {
 		return
 	}
@@ -133,9 +224,15 @@ type writer interface {
 	TryWriteByte(b byte)
 }
 
+// progressInterval is the number of input bytes between two calls to a progress callback
+const progressInterval = 4096
+
 // write compresses the data and writes it to the writer
 // note that this is meant to be stateless and not modify the compressor object.
-func (compressor *Compressor) write(w writer, d []byte, startIndex int, inputIndex *suffixarray.Index) (n int, err error) {
+// if progress is not nil, it is called with the number of bytes compressed so far
+// every progressInterval bytes, and once more at the end. If it returns an error,
+// compression is aborted and the error returned.
+func (compressor *Compressor) write(w writer, d []byte, startIndex int, finder MatchFinder, progress func(done, total int) error) (n int, err error) {
 	dictLen := len(compressor.dictData)
 
 	shortType := NewShortBackrefType()
@@ -157,8 +254,8 @@ func (compressor *Compressor) write(w writer, d []byte, startIndex int, inputInd
 			minLen = 1
 		}
 
-		bShort.address, bShort.length = findBackRef(d, at, shortType, minLen, inputIndex, compressor.dictIndex, dictLen)
-		bDynamic.address, bDynamic.length = findBackRef(d, at, bDynamic.bType, minLen, inputIndex, compressor.dictIndex, dictLen)
+		bShort.address, bShort.length = findBackRef(d, at, shortType, minLen, finder, compressor.dictIndex, dictLen)
+		bDynamic.address, bDynamic.length = findBackRef(d, at, bDynamic.bType, minLen, finder, compressor.dictIndex, dictLen)
 
 		// we store the best backref in the circular buffer
 		var bestAtI backref
@@ -173,7 +270,15 @@ func (compressor *Compressor) write(w writer, d []byte, startIndex int, inputInd
 	}
 
 	const minRepeatingBytes = 160
+	nextProgress := startIndex + progressInterval
 	for i := startIndex; i < len(d); {
+		if progress != nil && i >= nextProgress {
+			if err = progress(i-startIndex, len(d)-startIndex); err != nil {
+				return
+			}
+			nextProgress = i + progressInterval - (i-startIndex)%progressInterval
+		}
+
 		// if we have a series of repeating bytes, we can do "RLE" using a short backref
 		// note that since all our backref have max len of (1<<maxBackrefLenLog2)
 		// we stop if we have a series of repeating bytes of length (1<<maxBackrefLenLog2)
@@ -240,6 +345,8 @@ func (compressor *Compressor) write(w writer, d []byte, startIndex int, inputInd
 				continue
 			}
 		}
+		// both probes are relative to i; skipping to i+2 means emitting d[i+1] as a literal,
+		// which is only possible if it is not a reserved symbol.
 		if i+2 < len(d) && canEncodeSymbol(d[i+1]) {
 			// maybe at i+2 ? (we already tried i+1)
 			if _, newSavings := bestBackref(i + 2); newSavings > bestSavings+2 {
@@ -256,6 +363,11 @@ func (compressor *Compressor) write(w writer, d []byte, startIndex int, inputInd
 		i += bestAtI.length
 	}
 
+	if progress != nil {
+		if err = progress(len(d)-startIndex, len(d)-startIndex); err != nil {
+			return
+		}
+	}
 	return len(d) - startIndex, nil
 }
 
@@ -289,13 +401,9 @@ func (cb *circularBuffer) best(at int) (backref, bool) {
 func (compressor *Compressor) Reset() {
 	compressor.noCompression = false
 	compressor.outBuf.Reset()
-	header := Header{
-		Version:       Version,
-		NoCompression: compressor.noCompression,
-	}
-	if _, err := header.WriteTo(&compressor.outBuf); err != nil {
-		panic(err)
-	}
+	// a failed Write may leave bits in the writer's cache; start from a clean one
+	compressor.bw = bitio.NewWriter(&compressor.outBuf)
+	compressor.writeHeader()
 	compressor.inBuf.Reset()
 	compressor.lastOutLen = compressor.outBuf.Len()
 	compressor.lastNbSkippedBits = 0
@@ -347,42 +455,150 @@ func (compressor *Compressor) Revert() error {
 // ConsiderBypassing switches to NoCompression if we get significant expansion instead of compression
 func (compressor *Compressor) ConsiderBypassing() (bypassed bool) {
 
-	if compressor.outBuf.Len() > compressor.inBuf.Len()+HeaderSize {
+	// both outBuf and a stored frame include the header; bypass iff it is strictly smaller
+	if compressor.outBuf.Len() > compressor.inBuf.Len()+compressor.headerSize() {
 		// compression was not worth it
-		compressor.noCompression = true
-		compressor.nbSkippedBits = 0
-		compressor.lastOutLen = compressor.lastInLen + HeaderSize
-		compressor.lastNbSkippedBits = 0
-		compressor.outBuf.Reset()
-		header := Header{Version: Version, NoCompression: compressor.noCompression}
-		if _, err := header.WriteTo(&compressor.outBuf); err != nil {
-			panic(err)
-		}
-		if _, err := compressor.outBuf.Write(compressor.inBuf.Bytes()); err != nil {
-			panic(err)
-		}
+		compressor.bypass()
 		return true
 	}
 	return false
 }
 
+// bypass replaces the output with a NoCompression frame holding all the input written so far
+func (compressor *Compressor) bypass() {
+	compressor.noCompression = true
+	compressor.nbSkippedBits = 0
+	compressor.lastOutLen = compressor.lastInLen + compressor.headerSize()
+	compressor.lastNbSkippedBits = 0
+	compressor.outBuf.Reset()
+	compressor.writeHeader()
+	if _, err := compressor.outBuf.Write(compressor.inBuf.Bytes()); err != nil {
+		panic(err)
+	}
+}
+
+// writeHeader writes the Magic prefix if enabled, and the header, to the empty output
+func (compressor *Compressor) writeHeader() {
+	if compressor.magic {
+		compressor.outBuf.WriteString(Magic)
+	}
+	header := Header{Version: Version, NoCompression: compressor.noCompression}
+	b, _ := header.MarshalBinary()
+	compressor.outBuf.Write(b)
+}
+
+// headerSize returns the number of bytes written by writeHeader
+func (compressor *Compressor) headerSize() int {
+	if compressor.magic {
+		return len(Magic) + HeaderSize
+	}
+	return HeaderSize
+}
+
 // Bytes returns the compressed data
 func (compressor *Compressor) Bytes() []byte {
 	return compressor.outBuf.Bytes()
 }
 
 // Compress compresses the given data and returns the compressed data
+// On error, the compressor is Reset so that it can be used again right away.
 func (compressor *Compressor) Compress(d []byte) (c []byte, err error) {
 	compressor.Reset()
-	_, err = compressor.Write(d)
-	return compressor.Bytes(), err
+	if _, err = compressor.Write(d); err != nil {
+		compressor.Reset()
+		return nil, err
+	}
+	return compressor.Bytes(), nil
+}
+
+// Compress compresses data with a throwaway compressor using the given dictionary.
+// It is a convenience for one-off calls; to compress several inputs, create a
+// Compressor once with NewCompressor and reuse it.
+func Compress(data, dict []byte) ([]byte, error) {
+	compressor, err := NewCompressor(dict)
+	if err != nil {
+		return nil, err
+	}
+	return compressor.Compress(data)
+}
+
+// CompressWithProgress is like Compress, but calls progress(done, len(d)) every time
+// roughly 4096 more bytes of d have been compressed, and once more when it is done.
+// progress is called from the compressing goroutine and must not use the compressor.
+// If progress is nil, it is equivalent to Compress.
+func (compressor *Compressor) CompressWithProgress(d []byte, progress func(done, total int)) (c []byte, err error) {
+	var p func(done, total int) error
+	if progress != nil {
+		p = func(done, total int) error {
+			progress(done, total)
+			return nil
+		}
+	}
+	compressor.Reset()
+	if _, err = compressor.writeWithProgress(d, p); err != nil {
+		compressor.Reset()
+		return nil, err
+	}
+	return compressor.Bytes(), nil
+}
+
+// ErrTimeout is returned by CompressWithTimeout when compression takes too long.
+var ErrTimeout = errors.New("compression timed out")
+
+// CompressWithTimeout is like Compress, but gives up compressing after roughly timeout.
+// It then returns ErrTimeout along with a valid NoCompression frame holding d,
+// so that the result can always be decompressed.
+// The deadline is checked every 4096 bytes of input while looking for back-references.
+// Building the match finder's index beforehand (a suffix array by default) cannot be
+// interrupted, and may overrun the deadline. A compression that completes is always returned.
+func (compressor *Compressor) CompressWithTimeout(d []byte, timeout time.Duration) (c []byte, err error) {
+	compressor.Reset()
+	if timeout <= 0 {
+		// no time to compress at all
+		if err = compressor.appendInput(d); err != nil {
+			compressor.Reset()
+			return nil, err
+		}
+		compressor.bypass()
+		return compressor.Bytes(), ErrTimeout
+	}
+
+	var timedOut atomic.Bool
+	timer := time.AfterFunc(timeout, func() { timedOut.Store(true) })
+	defer timer.Stop()
+
+	_, err = compressor.writeWithProgress(d, timeoutProgress(&timedOut))
+	if errors.Is(err, ErrTimeout) {
+		// d was entirely written to inBuf before compressing; store it as is.
+		// the aborted write may have left bits in the writer's cache.
+		compressor.bw = bitio.NewWriter(&compressor.outBuf)
+		compressor.bypass()
+		return compressor.Bytes(), err
+	}
+	if err != nil {
+		compressor.Reset()
+		return nil, err
+	}
+	return compressor.Bytes(), nil
+}
+
+// timeoutProgress returns a progress callback aborting compression with ErrTimeout once timedOut is set.
+// The last call, made once everything is compressed, never aborts: the work is done.
+func timeoutProgress(timedOut *atomic.Bool) func(done, total int) error {
+	return func(done, total int) error {
+		if done < total && timedOut.Load() {
+			return ErrTimeout
+		}
+		return nil
+	}
 }
 
 // CompressedSize256k returns the size of the compressed data
 // This is state less and thread-safe (but other methods are not)
+// as long as the default MatchFinder is used; a custom one is shared with the compressor.
 // Max size of d is 256kB
 func (compressor *Compressor) CompressedSize256k(d []byte) (size int, err error) {
-	size = HeaderSize
+	size = compressor.headerSize()
 	if compressor.noCompression {
 		size += len(d)
 		return
@@ -393,11 +609,15 @@ func (compressor *Compressor) CompressedSize256k(d []byte) (size int, err error)
 	}
 
 	// build the index
-	var indexSpace [maxInputSize]int32 // should be allocated on the stack.
-	index := suffixarray.New(d, indexSpace[:len(d)])
+	finder := compressor.matchFinder
+	if _, ok := finder.(*SuffixArrayFinder); ok {
+		var indexSpace [maxInputSize]int32
+		finder = &SuffixArrayFinder{sa: indexSpace[:len(d)]}
+	}
+	finder.Reset(d)
 
 	bw := &bitCounterWriter{}
-	_, err = compressor.write(bw, d, 0, index)
+	_, err = compressor.write(bw, d, 0, finder, nil)
 	if err != nil {
 		return
 	}
@@ -432,27 +652,30 @@ func canEncodeSymbol(b byte) bool {
 // findBackRef attempts to find a backref in the window [i-brAddressRange, i+brLengthRange]
 // if no backref is found, it returns -1, -1
 // else returns the address and length of the backref
-func findBackRef(data []byte, i int, bType BackrefType, minLength int, dataIndex, dictIndex *suffixarray.Index, dictLen int) (addr, length int) {
+func findBackRef(data []byte, i int, bType BackrefType, minLength int, finder MatchFinder, dictIndex *suffixarray.Index, dictLen int) (addr, length int) {
 	if minLength == -1 {
 		minLength = bType.nbBytesBackRef
 	}
+	// lengths are encoded with a bias of 1: anything shorter can't be represented
+	if minLength < 1 {
+		minLength = 1
+	}
 
 	if i+minLength > len(data) {
 		return -1, -1
 	}
 
-	windowStart := max(0, i-bType.maxAddress)
-	maxLength := 1 << maxBackrefLenLog2
-	if i+maxLength > len(data) {
-		maxLength = len(data) - i
-	}
-
+	maxLength := min(bType.maxLength, len(data)-i)
 	if minLength > maxLength {
 		return -1, -1
 	}
 
-	// we look for data[i:i+maxLength) in the window data[windowStart:i)
-	addr, length = dataIndex.LookupLongest(data[i:i+maxLength], minLength, maxLength, windowStart, i)
+	// we look for data[i:i+maxLength) in the address window before i
+	addr, length = finder.FindLongest(data, i, bType, minLength)
+	if !isValidMatch(data, i, bType, minLength, maxLength, addr, length) {
+		// the finder may be user provided; a bad match would corrupt the output
+		addr, length = -1, -1
+	}
 	if bType.Delimiter == SymbolDynamic {
 		addr += dictLen
 	}
@@ -469,9 +692,19 @@ func findBackRef(data []byte, i int, bType BackrefType, minLength int, dataIndex
 	return
 }
 
+// isValidMatch returns true if data[addr:addr+length] is a match for data[i:]
+// that a backref of type bType can encode, and of length in [minLength, maxLength].
+// The match may overlap with data[i:], as the decompressor copies byte by byte.
+func isValidMatch(data []byte, i int, bType BackrefType, minLength, maxLength, addr, length int) bool {
+	if addr < max(0, i-bType.maxAddress) || addr >= i || length < minLength || length > maxLength {
+		return false
+	}
+	return bytes.Equal(data[addr:addr+length], data[i:i+length])
+}
+
 func (compressor *Compressor) appendInput(d []byte) error {
-	if compressor.inBuf.Len()+len(d) > MaxInputSize {
-		return fmt.Errorf("input size must be <= %d", MaxInputSize)
+	if compressor.inBuf.Len()+len(d) > compressor.maxInputSize {
+		return fmt.Errorf("input size must be <= %d", compressor.maxInputSize)
 	}
 	compressor.lastInLen = compressor.inBuf.Len()
 	compressor.inBuf.Write(d)
@@ -484,3 +717,10 @@ func max(a, b int) int {
 	}
 	return b
 }
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
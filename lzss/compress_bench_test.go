@@ -0,0 +1,69 @@
+package lzss
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/consensys/compress/lzss/bench"
+)
+
+// benchmarkInputs returns representative inputs for the compression benchmarks
+func benchmarkInputs(b *testing.B) map[string][]byte {
+	const size = 1 << 17
+
+	random := bytes.Join(bench.LoadRandomData(0), nil)
+	repeated := bytes.Join(bench.LoadRepetitiveData(), nil)
+	blocks := bytes.Join(bench.LoadEthereumBlocks(), nil)
+
+	calldata := getAverageBlock(b)
+
+	blob, err := os.ReadFile("./testdata/blobs/1-1865800")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return map[string][]byte{
+		"random":   random[:size],
+		"repeated": repeated[:size],
+		"blocks":   blocks[:size],
+		"calldata": calldata[:size],
+		"blob":     blob[:size],
+	}
+}
+
+// BenchmarkCompress reports the throughput and compression ratio
+// of every match finder on every benchmark input.
+func BenchmarkCompress(b *testing.B) {
+	dict := getDictionary()
+	inputs := benchmarkInputs(b)
+
+	finders := []struct {
+		name      string
+		newFinder func() MatchFinder
+	}{
+		{"suffixarray", func() MatchFinder { return NewSuffixArrayFinder() }},
+		{"hashchain", func() MatchFinder { return NewHashChainFinder(64) }},
+	}
+
+	for _, f := range finders {
+		compressor, err := NewCompressor(dict, WithMatchFinder(f.newFinder()))
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, name := range []string{"random", "repeated", "blocks", "calldata", "blob"} {
+			data := inputs[name]
+			b.Run(f.name+"/"+name, func(b *testing.B) {
+				var c []byte
+				b.SetBytes(int64(len(data)))
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if c, err = compressor.Compress(data); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(len(data))/float64(len(c)), "ratio")
+			})
+		}
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"encoding/hex"
 	"fmt"
 	"os"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/icza/bitio"
 	"github.com/stretchr/testify/assert"
@@ -67,8 +69,54 @@ func Test8ZerosAfterNonzero(t *testing.T) { // probably won't happen in our call
 	testCompressionRoundTrip(t, append([]byte{1}, make([]byte, 8)...))
 }
 
+// TestReservedSymbolNextToMatch places reserved symbols right before and right after
+// the start of a repeated pattern, so that the lookahead probes at i+1 and i+2
+// land on (or just past) a symbol that cannot be written as a literal.
+func TestReservedSymbolNextToMatch(t *testing.T) {
+	pattern := []byte("the quick brown fox jumps over the lazy dog")
+	dict := getDictionary()
+
+	for _, s := range []byte{SymbolShort, SymbolDynamic} {
+		inputs := [][]byte{
+			{s},
+			{1, s},
+			{s, 1},
+			{1, s, 2},
+			{s, s},
+		}
+		for _, sep := range inputs {
+			var d []byte
+			d = append(d, pattern...)
+			d = append(d, sep...)
+			d = append(d, pattern...)
+			d = append(d, sep...)
+			d = append(d, pattern[1:]...)
+
+			testCompressionRoundTrip(t, d)
+
+			compressor, err := NewCompressor(dict)
+			require.NoError(t, err)
+			c, err := compressor.Compress(d)
+			require.NoError(t, err)
+
+			// reserved symbols must never be emitted as literals
+			phrases, err := CompressedStreamInfo(c, dict)
+			require.NoError(t, err)
+			for _, p := range phrases {
+				if p.Type == 0 {
+					require.NotContains(t, p.Content, s, "reserved symbol emitted as a literal")
+				}
+			}
+		}
+	}
+}
+
 // Fuzz test the compression / decompression
 func FuzzCompress(f *testing.F) {
+	// each execution writes the input byte by byte, so keep the seeds short
+	for _, seed := range fuzzSeeds(f, 64) {
+		f.Add(seed, []byte{})
+	}
 
 	f.Fuzz(func(t *testing.T, input, dict []byte) {
 		if len(input) > MaxInputSize {
@@ -362,6 +410,272 @@ func TestRevert(t *testing.T) {
 	}
 }
 
+func TestCompressAfterError(t *testing.T) {
+	assert := require.New(t)
+	d := []byte("hello hello hello")
+
+	fresh, err := NewCompressor(nil)
+	assert.NoError(err)
+	expected, err := fresh.Compress(d)
+	assert.NoError(err)
+
+	compressor, err := NewCompressor(nil)
+	assert.NoError(err)
+
+	// leave the output unaligned, so that a failed Write has bits to leak
+	_, err = compressor.Write(d)
+	assert.NoError(err)
+	assert.NotZero(compressor.nbSkippedBits)
+
+	// fail mid-Write, after the bit writer cache has been reconstructed
+	_, err = compressor.Write(make([]byte, MaxInputSize))
+	assert.Error(err)
+
+	c, err := compressor.Compress(d)
+	assert.NoError(err)
+	assert.Equal(expected, c)
+
+	// a failing Compress must leave the compressor usable as well
+	_, err = compressor.Write(d)
+	assert.NoError(err)
+	c, err = compressor.Compress(make([]byte, MaxInputSize+1))
+	assert.Error(err)
+	assert.Nil(c)
+
+	c, err = compressor.Compress(d)
+	assert.NoError(err)
+	assert.Equal(expected, c)
+}
+
+func TestMaxSizeOptions(t *testing.T) {
+	assert := require.New(t)
+	dict := getDictionary()
+	data := getAverageBlock(t)[:2000]
+
+	compressor, err := NewCompressor(dict, WithMaxInputSize(1000))
+	assert.NoError(err)
+	assert.Equal(1000, compressor.maxInputSize)
+
+	c, err := compressor.Compress(data[:1000])
+	assert.NoError(err)
+	dBack, err := Decompress(c, dict)
+	assert.NoError(err)
+	assert.Equal(data[:1000], dBack)
+
+	_, err = compressor.Compress(data[:1001])
+	assert.Error(err)
+
+	// the limit applies to the total input since the last Reset
+	_, err = compressor.Write(data[:600])
+	assert.NoError(err)
+	_, err = compressor.Write(data[600:1200])
+	assert.Error(err)
+
+	// out of range values are clamped
+	_, err = NewCompressor(dict, WithMaxInputSize(MaxInputSize+1), WithMaxDictSize(-1))
+	assert.Error(err, "the dictionary can't fit in 0 bytes")
+	compressor, err = NewCompressor(dict, WithMaxInputSize(MaxInputSize+1), WithMaxDictSize(MaxDictSize+1))
+	assert.NoError(err)
+	assert.Equal(MaxInputSize, compressor.maxInputSize)
+	assert.Equal(MaxDictSize, compressor.maxDictSize)
+
+	// the dictionary size includes the reserved symbols
+	_, err = NewCompressor(dict, WithMaxDictSize(len(AugmentDict(dict))))
+	assert.NoError(err)
+	_, err = NewCompressor(dict, WithMaxDictSize(len(AugmentDict(dict))-1))
+	assert.Error(err)
+}
+
+func TestCompressWithProgress(t *testing.T) {
+	assert := require.New(t)
+	dict := getDictionary()
+	data := getAverageBlock(t)[:100000]
+
+	compressor, err := NewCompressor(dict)
+	assert.NoError(err)
+	expected, err := compressor.Compress(data)
+	assert.NoError(err)
+	expected = bytes.Clone(expected)
+
+	var calls []int
+	c, err := compressor.CompressWithProgress(data, func(done, total int) {
+		assert.Equal(len(data), total)
+		calls = append(calls, done)
+	})
+	assert.NoError(err)
+	assert.Equal(expected, c)
+
+	// one call per 4096 bytes, plus the final one
+	assert.Len(calls, len(data)/progressInterval+1)
+	for i := 1; i < len(calls); i++ {
+		assert.Greater(calls[i], calls[i-1])
+		assert.GreaterOrEqual(calls[i-1], i*progressInterval)
+	}
+	assert.Equal(len(data), calls[len(calls)-1])
+
+	// no callback
+	c, err = compressor.CompressWithProgress(data, nil)
+	assert.NoError(err)
+	assert.Equal(expected, c)
+}
+
+func TestCompressWithTimeout(t *testing.T) {
+	assert := require.New(t)
+	dict := getDictionary()
+	data := getAverageBlock(t)[:100000]
+
+	compressor, err := NewCompressor(dict)
+	assert.NoError(err)
+	expected, err := compressor.Compress(data)
+	assert.NoError(err)
+	expected = bytes.Clone(expected)
+
+	// plenty of time
+	c, err := compressor.CompressWithTimeout(data, time.Hour)
+	assert.NoError(err)
+	assert.Equal(expected, c)
+
+	// already expired: the input is stored
+	c, err = compressor.CompressWithTimeout(data, 0)
+	assert.ErrorIs(err, ErrTimeout)
+	assert.Len(c, HeaderSize+len(data))
+	d, err := Decompress(c, dict)
+	assert.NoError(err)
+	assert.Equal(data, d)
+
+	// the compressor can be appended to after a timeout, and used again normally
+	_, err = compressor.Write(data[:10])
+	assert.NoError(err)
+	d, err = Decompress(compressor.Bytes(), dict)
+	assert.NoError(err)
+	assert.Equal(append(bytes.Clone(data), data[:10]...), d)
+
+	c, err = compressor.Compress(data)
+	assert.NoError(err)
+	assert.Equal(expected, c)
+
+	// inputs too large are rejected, whatever the timeout
+	for _, timeout := range []time.Duration{0, time.Hour} {
+		_, err = compressor.CompressWithTimeout(make([]byte, MaxInputSize+1), timeout)
+		assert.Error(err)
+		assert.NotErrorIs(err, ErrTimeout)
+	}
+}
+
+func TestCompressWithTimeoutMidway(t *testing.T) {
+	dict := getDictionary()
+	data := getAverageBlock(t)
+
+	for name, opts := range map[string][]Option{"plain": nil, "magic": {WithMagic()}} {
+		t.Run(name, func(t *testing.T) {
+			assert := require.New(t)
+			compressor, err := NewCompressor(dict, opts...)
+			assert.NoError(err)
+
+			// leave the compressor unaligned
+			_, err = compressor.Write(data[:1001])
+			assert.NoError(err)
+
+			// the timer fires while the index is built; compression aborts at its first check
+			c, err := compressor.CompressWithTimeout(data, time.Nanosecond)
+			assert.ErrorIs(err, ErrTimeout)
+			assert.Len(c, compressor.headerSize()+len(data))
+			d, err := Decompress(c, dict)
+			assert.NoError(err)
+			assert.True(bytes.Equal(data, d), "round trip failed")
+
+			// the stored frame can be appended to and reverted
+			_, err = compressor.Write([]byte("hello"))
+			assert.NoError(err)
+			d, err = Decompress(compressor.Bytes(), dict)
+			assert.NoError(err)
+			assert.True(bytes.Equal(append(bytes.Clone(data), "hello"...), d))
+
+			assert.NoError(compressor.Revert())
+			d, err = Decompress(compressor.Bytes(), dict)
+			assert.NoError(err)
+			assert.True(bytes.Equal(data, d))
+		})
+	}
+}
+
+func TestTimeoutProgress(t *testing.T) {
+	assert := require.New(t)
+	var timedOut atomic.Bool
+	progress := timeoutProgress(&timedOut)
+
+	assert.NoError(progress(4096, 10000))
+	assert.NoError(progress(10000, 10000))
+
+	// once expired, only unfinished work is aborted
+	timedOut.Store(true)
+	assert.ErrorIs(progress(4096, 10000), ErrTimeout)
+	assert.NoError(progress(10000, 10000))
+	assert.NoError(progress(0, 0))
+}
+
+func TestPackageCompress(t *testing.T) {
+	assert := require.New(t)
+	dict := getDictionary()
+	data := getAverageBlock(t)[:10000]
+
+	compressor, err := NewCompressor(dict)
+	assert.NoError(err)
+	expected, err := compressor.Compress(data)
+	assert.NoError(err)
+
+	c, err := Compress(data, dict)
+	assert.NoError(err)
+	assert.Equal(expected, c)
+
+	d, err := Decompress(c, dict)
+	assert.NoError(err)
+	assert.Equal(data, d)
+
+	_, err = Compress(data, make([]byte, MaxDictSize+1))
+	assert.Error(err)
+}
+
+func TestAugmentDictWithSymbols(t *testing.T) {
+	assert := require.New(t)
+
+	assert.Equal([]byte{SymbolShort, SymbolDynamic}, AugmentDictWithSymbols(nil, SymbolShort, SymbolDynamic))
+	assert.Equal([]byte{1, SymbolShort, 2, SymbolDynamic}, AugmentDictWithSymbols([]byte{1, SymbolShort, 2}, SymbolShort, SymbolDynamic))
+	assert.Equal([]byte{1, 2, 3}, AugmentDictWithSymbols([]byte{1, 2}, 3, 3, 1))
+	assert.Equal([]byte{1, 2}, AugmentDictWithSymbols([]byte{1, 2}))
+
+	// AugmentDict keeps appending both symbols when only one is missing
+	assert.Equal([]byte{SymbolShort, SymbolShort, SymbolDynamic}, AugmentDict([]byte{SymbolShort}))
+	assert.Equal([]byte{SymbolDynamic, SymbolShort}, AugmentDict([]byte{SymbolDynamic, SymbolShort}))
+}
+
+func TestDictEntropy(t *testing.T) {
+	assert := require.New(t)
+
+	entropy := func(dict []byte) float64 {
+		compressor, err := NewCompressor(dict)
+		assert.NoError(err)
+		return compressor.DictEntropy()
+	}
+
+	// only the two reserved symbols
+	assert.InDelta(1, entropy(nil), 1e-9)
+	// four equally frequent values
+	assert.InDelta(2, entropy([]byte{0, 1, SymbolShort, SymbolDynamic}), 1e-9)
+
+	// every byte value exactly once
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+	assert.InDelta(8, entropy(all), 1e-9)
+
+	// one dominant value
+	low := entropy(append(make([]byte, 1000), all...))
+	assert.Greater(low, 0.0)
+	assert.Less(low, 8.0)
+}
+
 func TestInvalidBackref(t *testing.T) {
 	shortType := NewShortBackrefType()
 
@@ -425,6 +739,52 @@ func TestCraftExpandingInput(t *testing.T) {
 	assert.Less(compressor.Len(), lenC, "should have switched to NoCompression")
 }
 
+// TestConsiderBypassingThreshold crafts inputs whose compressed size lands
+// around the size of a stored (uncompressed) frame, and checks that
+// ConsiderBypassing always keeps the smaller of the two representations.
+func TestConsiderBypassingThreshold(t *testing.T) {
+	assert := require.New(t)
+
+	compressor, err := NewCompressor(nil)
+	assert.NoError(err)
+
+	// each reserved symbol costs a dynamic backref, each repeated byte saves a bit more than a literal
+	covered := make(map[int]bool)
+	for nbReserved := 0; nbReserved <= 2; nbReserved++ {
+		for repeatLen := 0; repeatLen <= 12; repeatLen++ {
+			var d []byte
+			for i := 0; i < 12; i++ {
+				d = append(d, byte(0x10+i))
+			}
+			for i := 0; i < nbReserved; i++ {
+				d = append(d, SymbolDynamic)
+			}
+			d = append(d, d[:repeatLen]...)
+
+			c, err := compressor.Compress(d)
+			assert.NoError(err)
+			compressedLen := len(c)
+			storedLen := len(d) + HeaderSize
+			covered[compressedLen-storedLen] = true
+
+			compressor.Reset()
+			_, err = compressor.Write(d)
+			assert.NoError(err)
+			assert.Equal(compressedLen > storedLen, compressor.ConsiderBypassing(), "reserved: %d, repeat: %d", nbReserved, repeatLen)
+			assert.Equal(min(compressedLen, storedLen), compressor.Len(), "reserved: %d, repeat: %d", nbReserved, repeatLen)
+
+			dBack, err := Decompress(compressor.Bytes(), nil)
+			assert.NoError(err)
+			assert.Equal(d, dBack)
+		}
+	}
+
+	// make sure we actually hit the boundary
+	for _, delta := range []int{-1, 0, 1} {
+		assert.True(covered[delta], "no input compressed to stored size %+d", delta)
+	}
+}
+
 func craftExpandingInput(dict []byte, size int) []byte {
 	const nbBytesExpandingBlock = 4 // TODO @gbotrel check that
 
@@ -588,9 +948,49 @@ func BenchmarkCompressedSize(b *testing.B) {
 	}
 }
 
-func min(a, b int) int {
-	if a < b {
-		return a
+func TestMagic(t *testing.T) {
+	assert := require.New(t)
+	dict := getDictionary()
+	data := getAverageBlock(t)[:10000]
+
+	plain, err := NewCompressor(dict)
+	assert.NoError(err)
+	magic, err := NewCompressor(dict, WithMagic())
+	assert.NoError(err)
+
+	expected, err := plain.Compress(data)
+	assert.NoError(err)
+	c, err := magic.Compress(data)
+	assert.NoError(err)
+	assert.Equal(Magic+string(expected), string(c))
+
+	d, err := Decompress(c, dict)
+	assert.NoError(err)
+	assert.Equal(data, d)
+
+	size, err := magic.CompressedSize256k(data)
+	assert.NoError(err)
+	assert.Equal(len(c), size)
+
+	// stored frames whose payload starts with the magic bytes
+	payload := []byte(Magic + "\x00\x01\x00\xfe\xff")
+	for _, compressor := range []*Compressor{plain, magic} {
+		compressor.Reset()
+		_, err = compressor.Write(payload)
+		assert.NoError(err)
+		assert.True(compressor.ConsiderBypassing())
+		assert.Equal(compressor.headerSize()+len(payload), compressor.Len())
+
+		d, err = Decompress(compressor.Bytes(), dict)
+		assert.NoError(err)
+		assert.Equal(payload, d)
+
+		// appending and reverting take the prefix into account
+		_, err = compressor.Write([]byte("hi"))
+		assert.NoError(err)
+		assert.NoError(compressor.Revert())
+		d, err = Decompress(compressor.Bytes(), dict)
+		assert.NoError(err)
+		assert.Equal(payload, d)
 	}
-	return b
 }
@@ -0,0 +1,78 @@
+package lzss
+
+import (
+	"bytes"
+	"sync"
+)
+
+// SyncCompressor serializes calls to a Compressor.
+// It is safe for concurrent use.
+type SyncCompressor struct {
+	lock       sync.Mutex
+	compressor *Compressor
+}
+
+// NewSyncCompressor returns a SyncCompressor with the given dictionary and options.
+// See NewCompressor.
+func NewSyncCompressor(dict []byte, opts ...Option) (*SyncCompressor, error) {
+	c, err := NewCompressor(dict, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &SyncCompressor{compressor: c}, nil
+}
+
+// Compress compresses the given data and returns the compressed data.
+// Unlike Compressor.Compress, the result is a copy and remains valid after subsequent calls.
+func (c *SyncCompressor) Compress(d []byte) ([]byte, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	res, err := c.compressor.Compress(d)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.Clone(res), nil
+}
+
+// CompressorPool is a sync.Pool of Compressors sharing the same dictionary and options.
+// Compressors are expensive to build (dictionary index, buffers and suffix array space),
+// so reusing them across calls and goroutines saves a lot of allocation.
+type CompressorPool struct {
+	pool sync.Pool
+}
+
+// NewCompressorPool returns a pool of Compressors created with NewCompressor(dict, opts...).
+// The options are applied to every Compressor of the pool, so they must not share
+// mutable state; in particular, a MatchFinder must not be passed through WithMatchFinder.
+func NewCompressorPool(dict []byte, opts ...Option) (*CompressorPool, error) {
+	// check the parameters once, so that the pool never has to fail
+	c, err := NewCompressor(dict, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &CompressorPool{}
+	p.pool.New = func() any {
+		c, err := NewCompressor(dict, opts...)
+		if err != nil {
+			panic(err) // already checked
+		}
+		return c
+	}
+	p.pool.Put(c)
+	return p, nil
+}
+
+// Get returns a Compressor from the pool, creating one if necessary.
+// It is not safe for concurrent use and should be returned with Put when done.
+func (p *CompressorPool) Get() *Compressor {
+	return p.pool.Get().(*Compressor)
+}
+
+// Put resets the Compressor and returns it to the pool.
+// Neither the Compressor nor any data obtained from it (e.g. through Bytes) may be used afterwards.
+func (p *CompressorPool) Put(c *Compressor) {
+	c.Reset()
+	p.pool.Put(c)
+}
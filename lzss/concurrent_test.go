@@ -0,0 +1,113 @@
+package lzss
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncCompressor(t *testing.T) {
+	assert := require.New(t)
+	dict := getDictionary()
+	data := getAverageBlock(t)
+
+	compressor, err := NewSyncCompressor(dict)
+	assert.NoError(err)
+
+	const nbRoutines = 8
+	var wg sync.WaitGroup
+	results := make([][]byte, nbRoutines)
+	errs := make([]error, nbRoutines)
+	for i := 0; i < nbRoutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = compressor.Compress(data[i*1000 : (i+2)*1000])
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range results {
+		assert.NoError(errs[i])
+		d, err := Decompress(results[i], dict)
+		assert.NoError(err)
+		assert.Equal(data[i*1000:(i+2)*1000], d)
+	}
+}
+
+func TestCompressorPool(t *testing.T) {
+	assert := require.New(t)
+	dict := getDictionary()
+	data := getAverageBlock(t)[:1<<14]
+
+	pool, err := NewCompressorPool(dict)
+	assert.NoError(err)
+
+	const nbRoutines = 8
+	var wg sync.WaitGroup
+	results := make([][]byte, nbRoutines)
+	errs := make([]error, nbRoutines)
+	for i := 0; i < nbRoutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c := pool.Get()
+			defer pool.Put(c)
+			// the output is only valid until c goes back to the pool
+			res, err := c.Compress(data)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			results[i], errs[i] = Decompress(res, dict)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range results {
+		assert.NoError(errs[i])
+		assert.Equal(data, results[i])
+	}
+
+	_, err = NewCompressorPool(make([]byte, MaxDictSize+1))
+	assert.Error(err)
+}
+
+func BenchmarkCompressorPool(b *testing.B) {
+	dict := getDictionary()
+	data := []byte("hello world, hello wordl")
+
+	b.Run("pool", func(b *testing.B) {
+		pool, err := NewCompressorPool(dict)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				c := pool.Get()
+				if _, err := c.Compress(data); err != nil {
+					b.Error(err)
+				}
+				pool.Put(c)
+			}
+		})
+	})
+
+	b.Run("new", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				c, err := NewCompressor(dict)
+				if err != nil {
+					b.Error(err)
+					return
+				}
+				if _, err := c.Compress(data); err != nil {
+					b.Error(err)
+				}
+			}
+		})
+	})
+}
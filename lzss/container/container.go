@@ -0,0 +1,215 @@
+// Package container stores many lzss compressed blocks in a single file,
+// with enough metadata to extract and verify any one of them on its own.
+//
+// A container is the concatenation of the compressed blocks, followed by an
+// index with one entry per block, and a fixed size footer:
+//
+//	block 0 | block 1 | ... | index | footer
+//
+// An index entry is 20 bytes: the block's offset in the container (8 bytes), its compressed size,
+// uncompressed size and CRC-32 (IEEE) checksum of the compressed bytes (4 bytes each).
+// The footer is 16 bytes: the offset of the index (8 bytes), the number of blocks (4 bytes)
+// and FooterMagic. All integers are big-endian.
+//
+// Since the index is written last, blocks can be streamed out as they are compressed.
+package container
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	"github.com/consensys/compress/lzss"
+)
+
+const (
+	// FooterMagic ends every container.
+	FooterMagic = "lzsc"
+
+	indexEntrySize = 8 + 4 + 4 + 4
+	footerSize     = 8 + 4 + 4 // index offset, number of blocks, FooterMagic
+)
+
+var (
+	// ErrCorrupted is returned when the footer or index of a container is inconsistent.
+	ErrCorrupted = errors.New("container: corrupted footer or index")
+	// ErrChecksum is returned when a block does not match its checksum.
+	ErrChecksum = errors.New("container: block checksum mismatch")
+)
+
+// BlockInfo describes a block of a container.
+type BlockInfo struct {
+	Offset           int64  // offset of the compressed block in the container
+	CompressedSize   int    // size of the compressed block, including the lzss header
+	UncompressedSize int    // size of the original data
+	Checksum         uint32 // CRC-32 (IEEE) of the compressed block
+}
+
+// Writer writes a container to an underlying io.Writer.
+type Writer struct {
+	w          io.Writer
+	compressor *lzss.Compressor
+	offset     int64
+	index      []BlockInfo
+	err        error
+}
+
+// NewWriter returns a Writer compressing blocks with compressor and writing them to w.
+// The compressor is Reset before each block; it must not be used elsewhere until Close.
+func NewWriter(w io.Writer, compressor *lzss.Compressor) *Writer {
+	return &Writer{w: w, compressor: compressor}
+}
+
+// AddBlock compresses data and writes it to the container as a new block.
+// Data is stored as is if it would not compress.
+// After an error, the Writer cannot be used anymore.
+func (w *Writer) AddBlock(data []byte) error {
+	if w.err != nil {
+		return w.err
+	}
+
+	if _, err := w.compressor.Compress(data); err != nil {
+		w.err = err
+		return err
+	}
+	w.compressor.ConsiderBypassing()
+	c := w.compressor.Bytes()
+
+	if _, err := w.w.Write(c); err != nil {
+		w.err = err
+		return err
+	}
+
+	w.index = append(w.index, BlockInfo{
+		Offset:           w.offset,
+		CompressedSize:   len(c),
+		UncompressedSize: len(data),
+		Checksum:         crc32.ChecksumIEEE(c),
+	})
+	w.offset += int64(len(c))
+	return nil
+}
+
+// Close writes the index and the footer. It does not close the underlying io.Writer.
+func (w *Writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+
+	buf := make([]byte, 0, len(w.index)*indexEntrySize+footerSize)
+	for _, b := range w.index {
+		buf = binary.BigEndian.AppendUint64(buf, uint64(b.Offset))
+		buf = binary.BigEndian.AppendUint32(buf, uint32(b.CompressedSize))
+		buf = binary.BigEndian.AppendUint32(buf, uint32(b.UncompressedSize))
+		buf = binary.BigEndian.AppendUint32(buf, b.Checksum)
+	}
+	buf = binary.BigEndian.AppendUint64(buf, uint64(w.offset))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(w.index)))
+	buf = append(buf, FooterMagic...)
+
+	if _, err := w.w.Write(buf); err != nil {
+		w.err = err
+		return err
+	}
+	w.err = errors.New("container: writer closed")
+	return nil
+}
+
+// Reader gives random access to the blocks of a container.
+type Reader struct {
+	r     io.ReaderAt
+	dict  []byte
+	index []BlockInfo
+}
+
+// NewReader reads the index of the size bytes long container in r.
+// dict is the dictionary the blocks were compressed with.
+func NewReader(r io.ReaderAt, size int64, dict []byte) (*Reader, error) {
+	if size < footerSize {
+		return nil, fmt.Errorf("%w: %d bytes is too short for a footer", ErrCorrupted, size)
+	}
+	var footer [footerSize]byte
+	if err := readAt(r, footer[:], size-footerSize); err != nil {
+		return nil, err
+	}
+	if string(footer[12:]) != FooterMagic {
+		return nil, fmt.Errorf("%w: bad magic %q", ErrCorrupted, footer[12:])
+	}
+	indexOffset := binary.BigEndian.Uint64(footer[:8])
+	nbBlocks := uint64(binary.BigEndian.Uint32(footer[8:12]))
+
+	// the index must fit exactly between the blocks and the footer
+	if indexOffset > uint64(size) || uint64(size)-indexOffset != nbBlocks*indexEntrySize+footerSize {
+		return nil, fmt.Errorf("%w: index of %d blocks at offset %d in %d bytes", ErrCorrupted, nbBlocks, indexOffset, size)
+	}
+
+	buf := make([]byte, nbBlocks*indexEntrySize)
+	if err := readAt(r, buf, int64(indexOffset)); err != nil {
+		return nil, err
+	}
+	index := make([]BlockInfo, nbBlocks)
+	for i := range index {
+		e := buf[i*indexEntrySize:]
+		index[i] = BlockInfo{
+			Offset:           int64(binary.BigEndian.Uint64(e[:8])),
+			CompressedSize:   int(binary.BigEndian.Uint32(e[8:12])),
+			UncompressedSize: int(binary.BigEndian.Uint32(e[12:16])),
+			Checksum:         binary.BigEndian.Uint32(e[16:20]),
+		}
+		if index[i].Offset < 0 || uint64(index[i].Offset)+uint64(index[i].CompressedSize) > indexOffset {
+			return nil, fmt.Errorf("%w: block %d out of bounds", ErrCorrupted, i)
+		}
+	}
+
+	return &Reader{r: r, dict: dict, index: index}, nil
+}
+
+// BlockCount returns the number of blocks in the container.
+func (r *Reader) BlockCount() int {
+	return len(r.index)
+}
+
+// BlockInfo returns the metadata of block i, which must be in [0, BlockCount()).
+func (r *Reader) BlockInfo(i int) BlockInfo {
+	return r.index[i]
+}
+
+// DecompressBlock reads block i, checks it against its checksum, and returns it decompressed.
+func (r *Reader) DecompressBlock(i int) ([]byte, error) {
+	if i < 0 || i >= len(r.index) {
+		return nil, fmt.Errorf("container: block %d out of range [0, %d)", i, len(r.index))
+	}
+	info := r.index[i]
+
+	c := make([]byte, info.CompressedSize)
+	if err := readAt(r.r, c, info.Offset); err != nil {
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(c) != info.Checksum {
+		return nil, fmt.Errorf("%w: block %d", ErrChecksum, i)
+	}
+
+	d, err := lzss.Decompress(c, r.dict)
+	if err != nil {
+		return nil, fmt.Errorf("container: block %d: %w", i, err)
+	}
+	if len(d) != info.UncompressedSize {
+		return nil, fmt.Errorf("%w: block %d decompressed to %d bytes, expected %d", ErrCorrupted, i, len(d), info.UncompressedSize)
+	}
+	return d, nil
+}
+
+// readAt fills p from r at offset off. Per the io.ReaderAt contract, a read ending exactly
+// at the end of r may return io.EOF along with all the bytes; this is not an error.
+func readAt(r io.ReaderAt, p []byte, off int64) error {
+	n, err := r.ReadAt(p, off)
+	if n == len(p) {
+		return nil
+	}
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,213 @@
+package container
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/consensys/compress/lzss"
+	"github.com/consensys/compress/lzss/bench"
+	"github.com/stretchr/testify/require"
+)
+
+var testDict = []byte("some dictionary content, with some redundancy: content, dictionary")
+
+// buildContainer returns a container holding blocks
+func buildContainer(t *testing.T, blocks [][]byte) []byte {
+	t.Helper()
+	compressor, err := lzss.NewCompressor(testDict)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf, compressor)
+	for _, b := range blocks {
+		require.NoError(t, w.AddBlock(b))
+	}
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func testBlocks() [][]byte {
+	blocks := [][]byte{
+		{},
+		[]byte("hello hello hello hello"),
+		{0xff, 0xfe, 0xff}, // expands, so it is stored
+	}
+	blocks = append(blocks, bench.LoadEthereumBlocks()[:2]...)
+	return append(blocks, bench.LoadRandomData(0)[0])
+}
+
+func TestRoundTrip(t *testing.T) {
+	assert := require.New(t)
+	blocks := testBlocks()
+	c := buildContainer(t, blocks)
+
+	r, err := NewReader(bytes.NewReader(c), int64(len(c)), testDict)
+	assert.NoError(err)
+	assert.Equal(len(blocks), r.BlockCount())
+
+	// in reverse order, to check blocks are independent
+	for i := len(blocks) - 1; i >= 0; i-- {
+		info := r.BlockInfo(i)
+		assert.Equal(len(blocks[i]), info.UncompressedSize)
+		// the writer's compressor has no Magic prefix, and ConsiderBypassing bounds the overhead
+		assert.LessOrEqual(info.CompressedSize, len(blocks[i])+lzss.HeaderSize)
+
+		d, err := r.DecompressBlock(i)
+		assert.NoError(err)
+		assert.True(bytes.Equal(blocks[i], d), "block %d", i)
+	}
+	// blocks are contiguous, followed by the index
+	offset := int64(0)
+	for i := 0; i < r.BlockCount(); i++ {
+		assert.Equal(offset, r.BlockInfo(i).Offset)
+		offset += int64(r.BlockInfo(i).CompressedSize)
+	}
+	assert.Equal(int64(len(c)-len(blocks)*indexEntrySize-footerSize), offset)
+
+	_, err = r.DecompressBlock(len(blocks))
+	assert.Error(err)
+	_, err = r.DecompressBlock(-1)
+	assert.Error(err)
+}
+
+func TestEmpty(t *testing.T) {
+	assert := require.New(t)
+	c := buildContainer(t, nil)
+	assert.Len(c, footerSize)
+
+	r, err := NewReader(bytes.NewReader(c), int64(len(c)), testDict)
+	assert.NoError(err)
+	assert.Equal(0, r.BlockCount())
+}
+
+func TestWriterClosed(t *testing.T) {
+	assert := require.New(t)
+	compressor, err := lzss.NewCompressor(testDict)
+	assert.NoError(err)
+
+	w := NewWriter(&bytes.Buffer{}, compressor)
+	assert.NoError(w.Close())
+	assert.Error(w.AddBlock([]byte("late")))
+	assert.Error(w.Close())
+}
+
+func TestCorruptedIndexOffset(t *testing.T) {
+	c := buildContainer(t, testBlocks())
+	indexOffset := len(c) - footerSize
+
+	for _, offset := range []uint64{0, 1, uint64(indexOffset) - 1, uint64(indexOffset) + 1, uint64(len(c)), 1 << 63} {
+		corrupted := bytes.Clone(c)
+		binary.BigEndian.PutUint64(corrupted[indexOffset:], offset)
+		_, err := NewReader(bytes.NewReader(corrupted), int64(len(corrupted)), testDict)
+		require.ErrorIs(t, err, ErrCorrupted, "offset %d", offset)
+	}
+}
+
+func TestCorruptedIndex(t *testing.T) {
+	assert := require.New(t)
+	blocks := testBlocks()
+	c := buildContainer(t, blocks)
+	indexStart := len(c) - footerSize - len(blocks)*indexEntrySize
+
+	newReader := func(c []byte) error {
+		_, err := NewReader(bytes.NewReader(c), int64(len(c)), testDict)
+		return err
+	}
+
+	// block out of bounds
+	corrupted := bytes.Clone(c)
+	binary.BigEndian.PutUint32(corrupted[indexStart+8:], uint32(len(c)))
+	assert.ErrorIs(newReader(corrupted), ErrCorrupted)
+
+	// wrong number of blocks
+	corrupted = bytes.Clone(c)
+	binary.BigEndian.PutUint32(corrupted[len(c)-8:], uint32(len(blocks)+1))
+	assert.ErrorIs(newReader(corrupted), ErrCorrupted)
+
+	// bad magic
+	corrupted = bytes.Clone(c)
+	corrupted[len(c)-1] ^= 1
+	assert.ErrorIs(newReader(corrupted), ErrCorrupted)
+
+	// truncated
+	assert.ErrorIs(newReader(c[:len(c)-1]), ErrCorrupted)
+	assert.ErrorIs(newReader(c[:footerSize-1]), ErrCorrupted)
+}
+
+func TestCorruptedBlockChecksum(t *testing.T) {
+	assert := require.New(t)
+	blocks := testBlocks()
+	c := buildContainer(t, blocks)
+
+	r, err := NewReader(bytes.NewReader(c), int64(len(c)), testDict)
+	assert.NoError(err)
+
+	for i := 1; i < len(blocks); i++ {
+		info := r.BlockInfo(i)
+		corrupted := bytes.Clone(c)
+		corrupted[info.Offset+int64(info.CompressedSize)/2] ^= 0x10
+
+		r, err := NewReader(bytes.NewReader(corrupted), int64(len(corrupted)), testDict)
+		assert.NoError(err)
+		_, err = r.DecompressBlock(i)
+		assert.ErrorIs(err, ErrChecksum, "block %d", i)
+
+		// other blocks are unaffected
+		d, err := r.DecompressBlock(i - 1)
+		assert.NoError(err)
+		assert.True(bytes.Equal(blocks[i-1], d))
+	}
+}
+
+// eofReaderAt returns io.EOF along with the data for reads that reach the end, as io.ReaderAt allows
+type eofReaderAt struct {
+	data []byte
+}
+
+func (r eofReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 || off > int64(len(r.data)) {
+		return 0, errors.New("invalid offset")
+	}
+	n := copy(p, r.data[off:])
+	if off+int64(n) == int64(len(r.data)) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func TestReaderAtEOF(t *testing.T) {
+	assert := require.New(t)
+	blocks := testBlocks()
+	c := buildContainer(t, blocks)
+
+	r, err := NewReader(eofReaderAt{c}, int64(len(c)), testDict)
+	assert.NoError(err)
+	for i := range blocks {
+		d, err := r.DecompressBlock(i)
+		assert.NoError(err)
+		assert.True(bytes.Equal(blocks[i], d), "block %d", i)
+	}
+
+	// a container that is actually shorter than announced
+	_, err = NewReader(eofReaderAt{c[:len(c)-1]}, int64(len(c)), testDict)
+	assert.ErrorIs(err, io.ErrUnexpectedEOF)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestWriterError(t *testing.T) {
+	assert := require.New(t)
+	compressor, err := lzss.NewCompressor(testDict)
+	assert.NoError(err)
+
+	w := NewWriter(failingWriter{}, compressor)
+	assert.Error(w.AddBlock([]byte("data")))
+	assert.Error(w.Close())
+}
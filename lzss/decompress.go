@@ -3,7 +3,6 @@ package lzss
 import (
 	"bytes"
 	"encoding/hex"
-	"errors"
 	"fmt"
 	"strconv"
 
@@ -15,21 +14,20 @@ import (
 // Note that this is not a fail-safe decompressor, it will fail ungracefully if the data
 // has a different format than the one expected
 func Decompress(data, dict []byte) (d []byte, err error) {
-	in := bitio.NewReader(bytes.NewReader(data))
+	data = trimMagic(data)
 
 	// parse header
 	var header Header
-	sizeHeader, err := header.ReadFrom(in)
-	if err != nil {
+	if err = header.UnmarshalBinary(data); err != nil {
 		return nil, fmt.Errorf("failed to read header: %w", err)
 	}
-	if header.Version != Version {
-		return nil, errors.New("unsupported compressor version")
-	}
+	sizeHeader, _ := EncodedHeaderSize(header.Version)
 	if header.NoCompression {
 		return data[sizeHeader:], nil
 	}
 
+	in := bitio.NewReader(bytes.NewReader(data[sizeHeader:]))
+
 	// init dict and backref types
 	dict = AugmentDict(dict)
 
@@ -95,17 +93,15 @@ type CompressionPhrase struct {
 type CompressionPhrases []CompressionPhrase
 
 func CompressedStreamInfo(c, dict []byte) (CompressionPhrases, error) {
-	in := bitio.NewReader(bytes.NewReader(c))
+	c = trimMagic(c)
 
 	// parse header
 	var header Header
-	sizeHeader, err := header.ReadFrom(in)
+	err := header.UnmarshalBinary(c)
 	if err != nil {
 		return nil, err
 	}
-	if header.Version != Version {
-		panic("unsupported compressor version")
-	}
+	sizeHeader, _ := EncodedHeaderSize(header.Version)
 	if header.NoCompression {
 		return CompressionPhrases{{
 			Type:              0,
@@ -117,6 +113,7 @@ func CompressedStreamInfo(c, dict []byte) (CompressionPhrases, error) {
 		}}, nil
 	}
 
+	in := bitio.NewReader(bytes.NewReader(c[sizeHeader:]))
 	var res CompressionPhrases
 
 	// init dict and backref types
@@ -0,0 +1,80 @@
+package lzss
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fuzzSeeds returns the first maxSize bytes of the inputs under testdata/*/data.bin, and a few edge cases.
+// Larger seeds make each execution, and minimizing every interesting input, too slow for the
+// fuzzer to make progress. The whole files are covered by TestReferenceBlobs.
+func fuzzSeeds(f *testing.F, maxSize int) [][]byte {
+	files, err := filepath.Glob("./testdata/*/data.bin")
+	if err != nil {
+		f.Fatal(err)
+	}
+	var seeds [][]byte
+	for _, file := range files {
+		d, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		seeds = append(seeds, d[:min(len(d), maxSize)])
+	}
+	return append(seeds, []byte{}, []byte{SymbolShort, SymbolDynamic}, make([]byte, maxSize))
+}
+
+// FuzzDecompressCorrupted checks that corrupting a compressed frame never makes Decompress panic.
+// The round trip itself is covered by FuzzCompress.
+func FuzzDecompressCorrupted(f *testing.F) {
+	for _, seed := range fuzzSeeds(f, 256) {
+		f.Add(seed)
+	}
+	dict := getDictionary()
+
+	compressor, err := NewCompressor(dict)
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	f.Fuzz(func(t *testing.T, input []byte) {
+		if len(input) > MaxInputSize {
+			t.Skip("input too large")
+		}
+
+		c, err := compressor.Compress(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// flip a few bits of the payload; the result may be garbage or an error, but not a panic
+		if len(c) <= HeaderSize {
+			return
+		}
+		corrupted := bytes.Clone(c)
+		for i, b := range input {
+			if i >= 8 {
+				break
+			}
+			corrupted[HeaderSize+int(b)%(len(c)-HeaderSize)] ^= 1 << (i % 8)
+		}
+		_, _ = Decompress(corrupted, dict)
+		_, _ = Decompress(corrupted[:len(corrupted)-1], dict)
+	})
+}
+
+// FuzzDecompress feeds arbitrary bytes to the decompressor, which must return an error rather than panic.
+func FuzzDecompress(f *testing.F) {
+	dict := getDictionary()
+	f.Add([]byte{0, 1, 0, SymbolShort, 0xff, 0xff, 0xff, 0xff})
+	f.Add([]byte{0, 1, 0, SymbolDynamic, 0xff, 0xff, 0xff, 0xff})
+	f.Add([]byte{0, 1, 1, 'h', 'i'})
+	f.Add([]byte{0, 1, 2})
+
+	f.Fuzz(func(t *testing.T, c []byte) {
+		_, _ = Decompress(c, dict)
+		_, _ = Decompress(c, nil)
+	})
+}
@@ -3,15 +3,59 @@ package lzss
 import (
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
 )
 
 const (
 	// Version is the current release version of the compressor.
-	Version    = 1
+	// It must be incremented whenever a change to the compressed format would make
+	// an existing decompressor misread new data: header layout, phrase encoding,
+	// back-reference widths, reserved symbols or dictionary augmentation.
+	// Changes to the compressor that still produce data in the same format don't warrant a new version.
+	Version = 1
+	// HeaderSize is the size in bytes of the header starting every compressed data.
+	// It is the minimum overhead of compression: once ConsiderBypassing has been called,
+	// a compressed data is never more than HeaderSize bytes larger than the input,
+	// or HeaderSize+len(Magic) for compressors created WithMagic.
 	HeaderSize = 3
+	// Magic is an optional prefix identifying compressed data, written before the header
+	// by compressors created WithMagic: "zk" followed by the low byte of Version.
+	// Since a header starts with a 0 byte, data with and without the prefix cannot be confused.
+	Magic = "zk\x01"
 )
 
+// Flags of the third header byte, signaling optional features of a compressed data.
+// The other bits are reserved: the compressor never sets them, and reading a header
+// with any of them set fails with an UnsupportedFlagError.
+const (
+	FlagNoCompression byte = 1 << 0 // the data is stored as is
+
+	knownFlags = FlagNoCompression
+)
+
+// ErrIncompatibleHeader is returned when reading a header with reserved bits set,
+// presumably written by a later version of the format.
+var ErrIncompatibleHeader = errors.New("incompatible header: reserved bits are set")
+
+// ErrUnsupportedVersion is returned when decoding a header of a version this package cannot read.
+var ErrUnsupportedVersion = errors.New("unsupported compressor version")
+
+// UnsupportedFlagError is returned when reading a header with a reserved flag bit set.
+// It wraps ErrIncompatibleHeader.
+type UnsupportedFlagError struct {
+	Flag  byte // the lowest unsupported bit
+	Flags byte // the whole flags byte
+}
+
+func (e *UnsupportedFlagError) Error() string {
+	return fmt.Sprintf("%v: unsupported flag %#02x in %#02x", ErrIncompatibleHeader, e.Flag, e.Flags)
+}
+
+func (e *UnsupportedFlagError) Unwrap() error {
+	return ErrIncompatibleHeader
+}
+
 // Header is the header of a compressed data.
 // It contains the compressor release version and the compression level.
 type Header struct {
@@ -19,28 +63,130 @@ type Header struct {
 	NoCompression bool
 }
 
-func (s *Header) WriteTo(w io.Writer) (int64, error) {
-	if err := binary.Write(w, binary.BigEndian, uint16(s.Version)); err != nil {
-		return 0, err
+// EncodedHeaderSize returns the size in bytes of an encoded header of the given version,
+// or false if this package cannot read that version.
+func EncodedHeaderSize(version uint16) (size int, ok bool) {
+	if !IsCompatibleVersion(version) {
+		return 0, false
 	}
+	return HeaderSize, true
+}
+
+// Equal returns true if s and other describe the same format.
+func (s *Header) Equal(other Header) bool {
+	return s.Version == other.Version && s.NoCompression == other.NoCompression
+}
 
-	if _, err := w.Write([]byte{ind(s.NoCompression)}); err != nil {
-		return 2, err
+// MarshalBinary returns the encoded header: the big-endian version, followed by the flags byte.
+func (s *Header) MarshalBinary() ([]byte, error) {
+	b := make([]byte, HeaderSize)
+	binary.BigEndian.PutUint16(b, s.Version)
+	b[2] = s.Flags()
+	return b, nil
+}
+
+// UnmarshalBinary decodes a header from the beginning of data, which may hold more,
+// typically the whole compressed data; the size of the header is given by EncodedHeaderSize.
+// It returns ErrUnsupportedVersion for unknown versions, and an UnsupportedFlagError
+// if a reserved bit is set.
+func (s *Header) UnmarshalBinary(data []byte) error {
+	if len(data) < 2 {
+		return fmt.Errorf("reading header version: %w", io.ErrUnexpectedEOF)
+	}
+	version := binary.BigEndian.Uint16(data)
+	size, ok := EncodedHeaderSize(version)
+	if !ok {
+		return fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)
+	}
+	if len(data) < size {
+		return fmt.Errorf("reading header: %w", io.ErrUnexpectedEOF)
 	}
+	return s.decode(data[:size], headerConfig{})
+}
 
-	return HeaderSize, nil
+func (s *Header) WriteTo(w io.Writer) (int64, error) {
+	b, err := s.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(b)
+	return int64(n), err
+}
+
+// Flags returns the flags byte encoding the header's optional features.
+func (s *Header) Flags() byte {
+	return ind(s.NoCompression) * FlagNoCompression
 }
 
 func (s *Header) ReadFrom(r io.Reader) (int64, error) {
+	return s.ReadFromWithOptions(r)
+}
+
+// HeaderOption configures how a Header is read.
+type HeaderOption func(*headerConfig)
+
+type headerConfig struct {
+	ignoreReserved bool
+}
+
+// IgnoreReserved makes reading a Header lenient: reserved bits are ignored instead of rejected.
+// This is meant for experimenting with formats that use them.
+func IgnoreReserved() HeaderOption {
+	return func(c *headerConfig) {
+		c.ignoreReserved = true
+	}
+}
+
+// ReadFromWithOptions is like ReadFrom, with the given options.
+// By default, it returns an UnsupportedFlagError if any reserved bit is set.
+func (s *Header) ReadFromWithOptions(r io.Reader, opts ...HeaderOption) (int64, error) {
+	var cfg headerConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
 	var b [HeaderSize]byte
 	n, err := io.ReadFull(r, b[:])
 	if err != nil {
 		return int64(n), err
 	}
+	return int64(n), s.decode(b[:], cfg)
+}
+
+// decode parses a version 1 layout header
+func (s *Header) decode(b []byte, cfg headerConfig) error {
+	if unknown := b[2] &^ knownFlags; !cfg.ignoreReserved && unknown != 0 {
+		return &UnsupportedFlagError{Flag: unknown & -unknown, Flags: b[2]}
+	}
 
 	s.Version = binary.BigEndian.Uint16(b[:2])
-	s.NoCompression, err = indInv(b[2])
-	return int64(n), err
+	s.NoCompression = b[2]&FlagNoCompression != 0
+	return nil
+}
+
+// trimMagic returns c without its Magic prefix, if any
+func trimMagic(c []byte) []byte {
+	if len(c) >= len(Magic) && string(c[:len(Magic)]) == Magic {
+		return c[len(Magic):]
+	}
+	return c
+}
+
+// SniffFrame returns true if c looks like compressed data this package can decompress,
+// along with its header. Only the Magic prefix, if any, and the header are checked:
+// without the prefix, arbitrary data starting with a valid header is reported as a frame.
+func SniffFrame(c []byte) (bool, Header) {
+	var header Header
+	if err := header.UnmarshalBinary(trimMagic(c)); err != nil {
+		return false, Header{}
+	}
+	return true, header
+}
+
+// IsCompatibleVersion returns true if data compressed with version v can be decompressed by this package.
+// There are no legacy formats yet, so only the current Version is accepted.
+func IsCompatibleVersion(v uint16) bool {
+	return v == Version
 }
 
 // ind indicator function
@@ -50,14 +196,3 @@ func ind(b bool) byte {
 	}
 	return 0
 }
-
-// indInv is inverse to ind
-func indInv(b byte) (bool, error) {
-	if b == 0 {
-		return false, nil
-	}
-	if b == 1 {
-		return true, nil
-	}
-	return false, errors.New("expected 0 or 1")
-}
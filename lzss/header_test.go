@@ -2,6 +2,8 @@ package lzss
 
 import (
 	"bytes"
+	"encoding/hex"
+	"io"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -24,3 +26,186 @@ func TestHeaderRoundTrip(t *testing.T) {
 
 	assert.Equal(h, h2)
 }
+
+func TestIsCompatibleVersion(t *testing.T) {
+	assert := require.New(t)
+	assert.True(IsCompatibleVersion(Version))
+	assert.False(IsCompatibleVersion(0))
+	assert.False(IsCompatibleVersion(Version + 1))
+
+	// the decompressor rejects incompatible versions
+	h := Header{Version: Version + 1}
+	var buf bytes.Buffer
+	_, err := h.WriteTo(&buf)
+	assert.NoError(err)
+	_, err = Decompress(buf.Bytes(), nil)
+	assert.Error(err)
+}
+
+func TestHeaderReservedBits(t *testing.T) {
+	assert := require.New(t)
+
+	for _, b := range []byte{0x02, 0x03, 0x80, 0xff} {
+		raw := []byte{0, Version, b}
+
+		var h Header
+		_, err := h.ReadFrom(bytes.NewReader(raw))
+		assert.ErrorIs(err, ErrIncompatibleHeader)
+		var flagErr *UnsupportedFlagError
+		assert.ErrorAs(err, &flagErr)
+		assert.Equal(b, flagErr.Flags)
+		assert.Equal(b&^FlagNoCompression&-(b&^FlagNoCompression), flagErr.Flag)
+
+		_, err = h.ReadFromWithOptions(bytes.NewReader(raw), IgnoreReserved())
+		assert.NoError(err)
+		assert.Equal(Header{Version: Version, NoCompression: b&1 == 1}, h)
+
+		_, err = Decompress(append(raw, 'h', 'i'), nil)
+		assert.ErrorIs(err, ErrIncompatibleHeader)
+	}
+
+	for _, b := range []byte{0, 1} {
+		var h Header
+		_, err := h.ReadFrom(bytes.NewReader([]byte{0, Version, b}))
+		assert.NoError(err)
+		assert.Equal(b == 1, h.NoCompression)
+	}
+}
+
+func TestHeaderFlags(t *testing.T) {
+	assert := require.New(t)
+	assert.Equal(byte(0), (&Header{Version: Version}).Flags())
+	assert.Equal(FlagNoCompression, (&Header{Version: Version, NoCompression: true}).Flags())
+
+	var h Header
+	_, err := h.ReadFrom(bytes.NewReader([]byte{0, Version, 0x0c}))
+	var flagErr *UnsupportedFlagError
+	assert.ErrorAs(err, &flagErr)
+	assert.Equal(byte(0x04), flagErr.Flag)
+	assert.Equal("incompatible header: reserved bits are set: unsupported flag 0x04 in 0x0c", err.Error())
+}
+
+// TestVersion1Golden pins the bytes of version 1 frames, which must not change.
+func TestVersion1Golden(t *testing.T) {
+	assert := require.New(t)
+
+	for _, tc := range []struct {
+		header Header
+		golden []byte
+	}{
+		{Header{Version: 1}, []byte{0x00, 0x01, 0x00}},
+		{Header{Version: 1, NoCompression: true}, []byte{0x00, 0x01, 0x01}},
+	} {
+		var buf bytes.Buffer
+		n, err := tc.header.WriteTo(&buf)
+		assert.NoError(err)
+		assert.EqualValues(HeaderSize, n)
+		assert.Equal(tc.golden, buf.Bytes())
+
+		var h Header
+		_, err = h.ReadFrom(bytes.NewReader(tc.golden))
+		assert.NoError(err)
+		assert.Equal(tc.header, h)
+	}
+
+	// whole frames
+	compressor, err := NewCompressor(nil)
+	assert.NoError(err)
+	c, err := compressor.Compress([]byte("hello hello hello hello"))
+	assert.NoError(err)
+	assert.Equal("00010068656c6c6f20fe100014", hex.EncodeToString(c))
+
+	compressor.Reset()
+	_, err = compressor.Write([]byte{0xff, 0xfe})
+	assert.NoError(err)
+	assert.True(compressor.ConsiderBypassing())
+	assert.Equal("000101fffe", hex.EncodeToString(compressor.Bytes()))
+}
+
+func TestHeaderMarshalBinary(t *testing.T) {
+	assert := require.New(t)
+
+	for _, h := range []Header{{Version: Version}, {Version: Version, NoCompression: true}} {
+		b, err := h.MarshalBinary()
+		assert.NoError(err)
+		size, ok := EncodedHeaderSize(h.Version)
+		assert.True(ok)
+		assert.Len(b, size)
+
+		var buf bytes.Buffer
+		_, err = h.WriteTo(&buf)
+		assert.NoError(err)
+		assert.Equal(buf.Bytes(), b)
+
+		var h2 Header
+		assert.NoError(h2.UnmarshalBinary(b))
+		assert.True(h.Equal(h2))
+
+		// trailing data is not part of the header
+		var h3 Header
+		assert.NoError(h3.UnmarshalBinary(append(b, 1, 2, 3)))
+		assert.True(h.Equal(h3))
+	}
+
+	assert.False((&Header{Version: Version}).Equal(Header{Version: Version, NoCompression: true}))
+	assert.False((&Header{Version: Version}).Equal(Header{Version: Version + 1}))
+}
+
+func TestHeaderUnmarshalBinaryErrors(t *testing.T) {
+	assert := require.New(t)
+
+	// short buffers
+	for _, b := range [][]byte{nil, {0}, {0, Version}} {
+		var h Header
+		assert.ErrorIs(h.UnmarshalBinary(b), io.ErrUnexpectedEOF, "%x", b)
+		_, err := Decompress(b, nil)
+		assert.ErrorIs(err, io.ErrUnexpectedEOF)
+	}
+
+	// future versions, whose size is unknown
+	_, ok := EncodedHeaderSize(Version + 1)
+	assert.False(ok)
+	for _, b := range [][]byte{{0, Version + 1}, {0, Version + 1, 0}, {1, Version, 0xff, 0xff}} {
+		var h Header
+		assert.ErrorIs(h.UnmarshalBinary(b), ErrUnsupportedVersion)
+		_, err := Decompress(b, nil)
+		assert.ErrorIs(err, ErrUnsupportedVersion)
+		_, err = CompressedStreamInfo(b, nil)
+		assert.ErrorIs(err, ErrUnsupportedVersion)
+	}
+
+	// reserved bits
+	var h Header
+	var flagErr *UnsupportedFlagError
+	assert.ErrorAs(h.UnmarshalBinary([]byte{0, Version, 0x80}), &flagErr)
+}
+
+func TestMagicMatchesVersion(t *testing.T) {
+	// Magic must be updated along with Version
+	require.Equal(t, "zk"+string([]byte{byte(Version)}), Magic)
+}
+
+func TestSniffFrame(t *testing.T) {
+	assert := require.New(t)
+
+	for _, tc := range []struct {
+		c      []byte
+		ok     bool
+		header Header
+	}{
+		{[]byte{0, Version, 0}, true, Header{Version: Version}},
+		{[]byte(Magic + "\x00\x01\x01hi"), true, Header{Version: Version, NoCompression: true}},
+		// a stored frame holding the magic bytes
+		{[]byte("\x00\x01\x01" + Magic), true, Header{Version: Version, NoCompression: true}},
+		{[]byte(Magic), false, Header{}},
+		{[]byte(Magic + Magic), false, Header{}},
+		{[]byte("zk"), false, Header{}},
+		{[]byte{0, Version, 0x80}, false, Header{}},
+		{[]byte{0, Version + 1, 0}, false, Header{}},
+		{nil, false, Header{}},
+	} {
+		ok, header := SniffFrame(tc.c)
+		assert.Equal(tc.ok, ok, "%x", tc.c)
+		assert.Equal(tc.header, header, "%x", tc.c)
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"bytes"
 	"math"
 	"sort"
+	"time"
 )
 
 // Can change for testing
@@ -23,13 +24,22 @@ const realMaxData32 = math.MaxInt32
 
 // Index implements a suffix array for fast substring search.
 type Index struct {
-	data []byte
-	sa   []int32 // suffix array for data; sa.len() == len(data)
+	data      []byte
+	sa        []int32 // suffix array for data; sa.len() == len(data)
+	buildTime time.Duration
+}
+
+// IndexStats describes an [Index], for profiling.
+type IndexStats struct {
+	DataLen        int   // length of the indexed data
+	SuffixArrayLen int   // length of the suffix array
+	BuildTimeNs    int64 // time spent in New, in nanoseconds
 }
 
 // New creates a new [Index] for data.
 // [Index] creation time is O(N) for N = len(data).
 func New(data []byte, sa []int32) *Index {
+	start := time.Now()
 	ix := &Index{data: data}
 	if len(data) > maxData32 {
 		panic("suffixarray: data too large")
@@ -41,9 +51,19 @@ func New(data []byte, sa []int32) *Index {
 	ix.sa = sa[:len(data)]
 	text_32(data, ix.sa)
 
+	ix.buildTime = time.Since(start)
 	return ix
 }
 
+// Stats returns the sizes of the index and the time it took to build.
+func (x *Index) Stats() IndexStats {
+	return IndexStats{
+		DataLen:        len(x.data),
+		SuffixArrayLen: len(x.sa),
+		BuildTimeNs:    x.buildTime.Nanoseconds(),
+	}
+}
+
 // Bytes returns the data over which the index was created.
 // It must not be modified.
 func (x *Index) Bytes() []byte {
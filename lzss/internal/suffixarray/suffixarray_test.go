@@ -0,0 +1,42 @@
+package suffixarray
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	assert := require.New(t)
+
+	data := []byte("abracadabra")
+	x := New(data, make([]int32, 2*len(data)))
+	stats := x.Stats()
+	assert.Equal(len(data), stats.DataLen)
+	assert.Equal(len(data), stats.SuffixArrayLen)
+	assert.GreaterOrEqual(stats.BuildTimeNs, int64(0))
+
+	stats = New(nil, nil).Stats()
+	assert.Zero(stats.DataLen)
+	assert.Zero(stats.SuffixArrayLen)
+}
+
+// BenchmarkNew measures the construction of the suffix array, which is linear (SA-IS),
+// on inputs up to lzss.MaxInputSize.
+func BenchmarkNew(b *testing.B) {
+	const maxInputSize = 1 << 22 // lzss.MaxInputSize
+	data := make([]byte, maxInputSize)
+	rand.New(rand.NewSource(0)).Read(data) //#nosec G404 -- deterministic benchmark input
+	sa := make([]int32, maxInputSize)
+
+	for _, size := range []int{1 << 10, 1 << 16, maxInputSize} {
+		b.Run(fmt.Sprintf("%dB", size), func(b *testing.B) {
+			b.SetBytes(int64(size))
+			for i := 0; i < b.N; i++ {
+				New(data[:size], sa[:size])
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package lzss
+
+import (
+	"github.com/consensys/compress/lzss/internal/suffixarray"
+)
+
+// MatchFinder looks for earlier occurrences of the input in the input itself.
+// The compressor uses it to find back-reference candidates; matches in the
+// dictionary are always looked up separately.
+type MatchFinder interface {
+	// Reset prepares the finder to search in data.
+	// It is called every time the input changes, before any call to FindLongest.
+	Reset(data []byte)
+
+	// FindLongest returns the address and length of the longest match for data[pos:]
+	// that is at least minLen bytes long, starts within the address window of bType
+	// before pos, and is no longer than bType allows: the address must be in
+	// [pos - 1<<bType.NbBitsAddress, pos), and the length at most 1<<bType.NbBitsLength.
+	// The match may overlap with data[pos:].
+	// It returns -1, -1 if no such match exists.
+	// The compressor ignores matches that do not meet these conditions.
+	FindLongest(data []byte, pos int, bType BackrefType, minLen int) (addr, length int)
+}
+
+// SuffixArrayFinder is the default MatchFinder.
+// It always finds the longest match, using a suffix array over the input.
+type SuffixArrayFinder struct {
+	index *suffixarray.Index
+	sa    []int32 // suffix array space; grown as needed and reused across calls to Reset.
+}
+
+// NewSuffixArrayFinder returns a SuffixArrayFinder.
+// Its suffix array space is allocated on first use.
+func NewSuffixArrayFinder() *SuffixArrayFinder {
+	return &SuffixArrayFinder{}
+}
+
+func (f *SuffixArrayFinder) Reset(data []byte) {
+	if cap(f.sa) < len(data) {
+		f.sa = make([]int32, 0, max(len(data), 2*cap(f.sa)))
+	}
+	f.index = suffixarray.New(data, f.sa[:len(data)])
+}
+
+// IndexStats describes the suffix array built by a [SuffixArrayFinder], for profiling.
+type IndexStats = suffixarray.IndexStats
+
+// Stats returns the sizes of the suffix array built by the last call to Reset,
+// and the time it took to build. It returns zero stats before the first Reset.
+func (f *SuffixArrayFinder) Stats() IndexStats {
+	if f.index == nil {
+		return IndexStats{}
+	}
+	return f.index.Stats()
+}
+
+func (f *SuffixArrayFinder) FindLongest(data []byte, pos int, bType BackrefType, minLen int) (addr, length int) {
+	windowStart := max(0, pos-bType.maxAddress)
+	maxLength := min(bType.maxLength, len(data)-pos)
+	if minLen > maxLength {
+		return -1, -1
+	}
+
+	// we look for data[pos:pos+maxLength) in the window data[windowStart:pos)
+	return f.index.LookupLongest(data[pos:pos+maxLength], minLen, maxLength, windowStart, pos)
+}
+
+const (
+	hashChainLog2     = 16 // number of bits of the hash table index
+	hashChainMinMatch = 3  // number of bytes hashed; shorter matches are not found
+)
+
+// HashChainFinder is a MatchFinder that indexes every position of the input by
+// its first hashChainMinMatch bytes, chaining positions that share a hash.
+// Indexing is linear and cheap, but only the most recent candidates in each chain
+// are examined, and matches shorter than hashChainMinMatch bytes are never found:
+// it trades some compression ratio for speed.
+type HashChainFinder struct {
+	head     [1 << hashChainLog2]int32 // most recent position for each hash, -1 if none
+	prev     []int32                   // previous position with the same hash, -1 if none
+	maxChain int
+}
+
+// NewHashChainFinder returns a HashChainFinder that examines at most maxChain candidates per lookup.
+func NewHashChainFinder(maxChain int) *HashChainFinder {
+	return &HashChainFinder{maxChain: maxChain}
+}
+
+func hashChainKey(b []byte) uint32 {
+	v := uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+	return (v * 2654435761) >> (32 - hashChainLog2)
+}
+
+func (f *HashChainFinder) Reset(data []byte) {
+	for i := range f.head {
+		f.head[i] = -1
+	}
+	if cap(f.prev) < len(data) {
+		f.prev = make([]int32, len(data))
+	}
+	f.prev = f.prev[:len(data)]
+
+	for i := 0; i < len(data); i++ {
+		if i+hashChainMinMatch > len(data) {
+			f.prev[i] = -1
+			continue
+		}
+		h := hashChainKey(data[i:])
+		f.prev[i] = f.head[h]
+		f.head[h] = int32(i)
+	}
+}
+
+func (f *HashChainFinder) FindLongest(data []byte, pos int, bType BackrefType, minLen int) (addr, length int) {
+	addr, length = -1, -1
+	windowStart := max(0, pos-bType.maxAddress)
+	maxLength := min(bType.maxLength, len(data)-pos)
+	if minLen > maxLength || pos+hashChainMinMatch > len(data) {
+		return
+	}
+
+	// candidates are visited from the closest to the furthest
+	for j, depth := int(f.prev[pos]), 0; j >= windowStart && depth < f.maxChain; j, depth = int(f.prev[j]), depth+1 {
+		l := 0
+		for l < maxLength && data[j+l] == data[pos+l] {
+			l++
+		}
+		if l >= minLen && l > length {
+			addr, length = j, l
+			if l == maxLength {
+				break
+			}
+		}
+	}
+	return
+}
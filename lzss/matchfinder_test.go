@@ -0,0 +1,187 @@
+package lzss
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// naiveFinder tries every position in the window
+type naiveFinder struct{}
+
+func (naiveFinder) Reset([]byte) {}
+
+func (naiveFinder) FindLongest(data []byte, pos int, bType BackrefType, minLen int) (addr, length int) {
+	addr, length = -1, -1
+	maxLength := min(bType.maxLength, len(data)-pos)
+	for j := max(0, pos-bType.maxAddress); j < pos; j++ {
+		l := 0
+		for l < maxLength && data[j+l] == data[pos+l] {
+			l++
+		}
+		if l >= minLen && l > length {
+			addr, length = j, l
+		}
+	}
+	return
+}
+
+// blindFinder never finds anything
+type blindFinder struct{}
+
+func (blindFinder) Reset([]byte) {}
+
+func (blindFinder) FindLongest([]byte, int, BackrefType, int) (int, int) {
+	return -1, -1
+}
+
+// lyingFinder returns invalid matches, built from the naive finder's results
+type lyingFinder struct {
+	nbCalls int
+}
+
+func (*lyingFinder) Reset([]byte) {}
+
+func (f *lyingFinder) FindLongest(data []byte, pos int, bType BackrefType, minLen int) (addr, length int) {
+	addr, length = naiveFinder{}.FindLongest(data, pos, bType, minLen)
+	f.nbCalls++
+	switch f.nbCalls % 7 {
+	case 0:
+		return pos, minLen // in the future
+	case 1:
+		return pos + 10, minLen
+	case 2:
+		return pos - bType.maxAddress - 1, minLen // out of the window
+	case 3:
+		return max(addr, 0), bType.maxLength + 1 // too long
+	case 4:
+		return max(addr, 0), minLen - 1 // too short
+	case 5:
+		if addr > 0 {
+			return addr - 1, length // bytes don't match, most likely
+		}
+		return 0, len(data) - pos + 1 // past the end of the data
+	default:
+		return
+	}
+}
+
+func TestLyingMatchFinder(t *testing.T) {
+	assert := require.New(t)
+	dict := getDictionary()
+	data := getAverageBlock(t)[:1<<12]
+	data = append(data, make([]byte, 600)...) // a run longer than the max backref length
+
+	f := &lyingFinder{}
+	compressor, err := NewCompressor(dict, WithMatchFinder(f))
+	assert.NoError(err)
+
+	c, err := compressor.Compress(data)
+	assert.NoError(err)
+	assert.Greater(f.nbCalls, 1000)
+	dBack, err := Decompress(c, dict)
+	assert.NoError(err)
+	assert.True(bytes.Equal(data, dBack), "round trip failed")
+}
+
+func getAverageBlock(t testing.TB) []byte {
+	d, err := os.ReadFile("./testdata/average_block.hex")
+	require.NoError(t, err)
+	data, err := hex.DecodeString(string(d))
+	require.NoError(t, err)
+	return data
+}
+
+func TestMatchFinders(t *testing.T) {
+	dict := getDictionary()
+	data := getAverageBlock(t)[:1<<12]
+
+	reference, err := NewCompressor(dict)
+	require.NoError(t, err)
+	expected, err := reference.Compress(data)
+	require.NoError(t, err)
+
+	finders := map[string]MatchFinder{
+		"suffixarray": NewSuffixArrayFinder(),
+		"naive":       naiveFinder{},
+		"hashchain":   NewHashChainFinder(64),
+		"blind":       blindFinder{},
+	}
+
+	for name, finder := range finders {
+		t.Run(name, func(t *testing.T) {
+			assert := require.New(t)
+			compressor, err := NewCompressor(dict, WithMatchFinder(finder))
+			assert.NoError(err)
+
+			c, err := compressor.Compress(data)
+			assert.NoError(err)
+			dBack, err := Decompress(c, dict)
+			assert.NoError(err)
+			assert.True(bytes.Equal(data, dBack), "round trip failed")
+
+			size, err := compressor.CompressedSize256k(data)
+			assert.NoError(err)
+			assert.Equal(len(c), size)
+
+			switch name {
+			case "suffixarray":
+				assert.Equal(expected, c)
+			case "blind":
+				// only the dictionary can be referenced
+				assert.Greater(len(c), len(expected))
+			}
+		})
+	}
+}
+
+func TestSuffixArrayFinderGrowsLazily(t *testing.T) {
+	assert := require.New(t)
+	data := getAverageBlock(t)
+
+	finder := NewSuffixArrayFinder()
+	compressor, err := NewCompressor(getDictionary(), WithMatchFinder(finder))
+	assert.NoError(err)
+	assert.Zero(cap(finder.sa))
+
+	_, err = compressor.Write(data[:1000])
+	assert.NoError(err)
+	assert.GreaterOrEqual(cap(finder.sa), 1000)
+	assert.Less(cap(finder.sa), MaxInputSize)
+
+	// the space is reused when it is large enough
+	compressor.Reset()
+	sa := finder.sa
+	_, err = compressor.Write(data[:500])
+	assert.NoError(err)
+	assert.Equal(cap(sa), cap(finder.sa))
+	assert.Same(&sa[:1][0], &finder.sa[:1][0])
+
+	_, err = compressor.Write(data[500:5000])
+	assert.NoError(err)
+	assert.GreaterOrEqual(cap(finder.sa), 5000)
+}
+
+func TestSuffixArrayFinderStats(t *testing.T) {
+	assert := require.New(t)
+	data := getAverageBlock(t)
+
+	finder := NewSuffixArrayFinder()
+	compressor, err := NewCompressor(getDictionary(), WithMatchFinder(finder))
+	assert.NoError(err)
+	assert.Equal(IndexStats{}, finder.Stats())
+
+	_, err = compressor.Write(data[:1000])
+	assert.NoError(err)
+	_, err = compressor.Write(data[1000:3000])
+	assert.NoError(err)
+
+	// the index covers the whole input, not just the last write
+	stats := finder.Stats()
+	assert.Equal(3000, stats.DataLen)
+	assert.Equal(3000, stats.SuffixArrayLen)
+	assert.Positive(stats.BuildTimeNs)
+}
@@ -0,0 +1,49 @@
+// Package testutil provides inputs and assertions shared by the lzss tests.
+package testutil
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/consensys/compress/lzss"
+	"github.com/consensys/compress/lzss/bench"
+	"github.com/stretchr/testify/require"
+)
+
+// RandomInput returns size pseudo-random bytes, deterministically derived from seed.
+func RandomInput(seed int64, size int) []byte {
+	res := make([]byte, size)
+	rand.New(rand.NewSource(seed)).Read(res) //#nosec G404 -- test input
+	return res
+}
+
+// EthereumCalldata returns synthetic, deterministic EVM calldata for txCount transactions.
+// See [bench.EthereumCalldata].
+func EthereumCalldata(txCount int) []byte {
+	return bench.EthereumCalldata(txCount)
+}
+
+// AssertRoundTrip compresses input with c, checks that it decompresses back to input
+// using dict, and returns the compressed data.
+func AssertRoundTrip(t testing.TB, c *lzss.Compressor, dict, input []byte) []byte {
+	t.Helper()
+
+	compressed, err := c.Compress(input)
+	require.NoError(t, err)
+	compressed = bytes.Clone(compressed)
+
+	decompressed, err := lzss.Decompress(compressed, dict)
+	require.NoError(t, err)
+	require.True(t, bytes.Equal(input, decompressed), "round trip failed")
+
+	return compressed
+}
+
+// AssertCompressionRatio checks that compressing input into compressed achieved at least minRatio.
+func AssertCompressionRatio(t testing.TB, input, compressed []byte, minRatio float64) {
+	t.Helper()
+
+	ratio := float64(len(input)) / float64(len(compressed))
+	require.GreaterOrEqual(t, ratio, minRatio, "compression ratio %.2f below %.2f", ratio, minRatio)
+}
@@ -0,0 +1,30 @@
+package testutil
+
+import (
+	"testing"
+
+	"github.com/consensys/compress/lzss"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInputsAreDeterministic(t *testing.T) {
+	require.Equal(t, RandomInput(1, 100), RandomInput(1, 100))
+	require.NotEqual(t, RandomInput(1, 100), RandomInput(2, 100))
+	require.Len(t, RandomInput(3, 1000), 1000)
+
+	require.Equal(t, EthereumCalldata(10), EthereumCalldata(10))
+	require.Empty(t, EthereumCalldata(0))
+}
+
+func TestRoundTrips(t *testing.T) {
+	c, err := lzss.NewCompressor(nil)
+	require.NoError(t, err)
+
+	calldata := EthereumCalldata(500)
+	compressed := AssertRoundTrip(t, c, nil, calldata)
+	AssertCompressionRatio(t, calldata, compressed, 3)
+
+	random := RandomInput(0, 1<<12)
+	compressed = AssertRoundTrip(t, c, nil, random)
+	AssertCompressionRatio(t, random, compressed, 0.9)
+}